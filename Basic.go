@@ -16,6 +16,73 @@ Basic defines the set of "simple" algebraic datatypes:
   Ln  => The natural logarithm of a term
 */
 
+func init() {
+	register(TidS, Registration{
+		Arity:  0,
+		Valued: true,
+		New: func(val float64, _ []Term) (Term, error) {
+			return S(val), nil
+		},
+		Type: S(0),
+	})
+	register(TidX, Registration{
+		Name:  "x",
+		Arity: 0,
+		New: func(_ float64, _ []Term) (Term, error) {
+			return X{}, nil
+		},
+		Type: X{},
+	})
+	register(TidSx, Registration{
+		Arity:  0,
+		Valued: true,
+		New: func(val float64, _ []Term) (Term, error) {
+			return Sx{val}, nil
+		},
+		Type: Sx{},
+	})
+	register(TidExp, Registration{
+		Name:  "e",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Exp{X: args[0]}, nil
+		},
+		Type: Exp{},
+	})
+	register(TidTPT, Registration{
+		Name:  "^",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return TPT{A: args[0], B: args[1]}, nil
+		},
+		Type: TPT{},
+	})
+	register(TidTP, Registration{
+		Arity:  1,
+		Valued: true,
+		New: func(val float64, args []Term) (Term, error) {
+			return TP{X: args[0], P: val}, nil
+		},
+		Type: TP{},
+	})
+	register(TidPT, Registration{
+		Arity:  1,
+		Valued: true,
+		New: func(val float64, args []Term) (Term, error) {
+			return PT{V: val, X: args[0]}, nil
+		},
+		Type: PT{},
+	})
+	register(TidLn, Registration{
+		Name:  "ln",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Ln{X: args[0]}, nil
+		},
+		Type: Ln{},
+	})
+}
+
 type S float64
 
 func (e S) E(_ float64) float64 {
@@ -105,12 +172,14 @@ func (e Exp) Dx() Term {
 }
 
 func (e Exp) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if ok {
 		return S(math.Exp(val))
 	}
 
-	return Exp{e.X.T()}
+	return Exp{e.X}
 }
 
 func (e Exp) Is() (bool, float64) {
@@ -122,6 +191,15 @@ func (e Exp) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Exp) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Exp) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Exp) Tokenise() Tokens {
 	t := Tokens{{id: TidExp}}
 	t = append(t, e.X.Tokenise()...)
@@ -154,6 +232,8 @@ func (e TPT) Dx() Term {
 }
 
 func (e TPT) T() Term {
+	e = simplified(e)
+
 	aOk, aVal := e.A.Is()
 	bOk, bVal := e.B.Is()
 
@@ -163,19 +243,21 @@ func (e TPT) T() Term {
 		if aVal == 0 || aVal == 1 {
 			return S(aVal)
 		}
-		return PT{aVal, e.B.T()}
+		return PT{aVal, e.B}
 	} else if bOk {
 		if bVal == 0 {
 			return S(1)
 		} else if bVal == 1 {
 			return e.A
 		}
-		return TP{e.A.T(), bVal}
+		return TP{e.A, bVal}
+	} else if _, ok := e.A.(EulerE); ok {
+		return Exp{e.B}
 	}
 
 	return TPT{
-		e.A.T(),
-		e.B.T(),
+		e.A,
+		e.B,
 	}
 }
 
@@ -198,6 +280,15 @@ func (e TPT) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e TPT) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e TPT) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
 func (e TPT) Tokenise() Tokens {
 	t := Tokens{{id: TidTPT}}
 	t = append(t, e.A.Tokenise()...)
@@ -217,6 +308,7 @@ func (e TP) E(x float64) float64 {
 func (e TP) Dx() Term {
 	return Prod{
 		S(e.P),
+		e.X.Dx(),
 		TP{
 			X: e.X,
 			P: e.P - 1,
@@ -225,20 +317,22 @@ func (e TP) Dx() Term {
 }
 
 func (e TP) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if ok {
 		return S(math.Pow(val, e.P))
 	}
 
 	if e.P == 0 {
-		return S(0)
+		return S(1)
 	}
 
 	if e.P == 1 {
-		return e.X.T()
+		return e.X
 	}
 
-	return TP{e.X.T(), e.P}
+	return TP{e.X, e.P}
 }
 
 func (e TP) Is() (bool, float64) {
@@ -248,12 +342,21 @@ func (e TP) Is() (bool, float64) {
 	}
 
 	if e.P == 0 {
-		return true, 0
+		return true, 1
 	}
 
 	return false, 0
 }
 
+func (e TP) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e TP) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e TP) Tokenise() Tokens {
 	t := Tokens{{id: TidTPT}}
 	t = append(t, e.X.Tokenise()...)
@@ -282,13 +385,15 @@ func (e PT) Dx() Term {
 }
 
 func (e PT) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 
 	if ok {
 		return S(math.Pow(e.V, val))
 	}
 
-	return PT{e.V, e.X.T()}
+	return PT{e.V, e.X}
 }
 
 func (e PT) Is() (bool, float64) {
@@ -301,6 +406,15 @@ func (e PT) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e PT) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e PT) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e PT) Tokenise() Tokens {
 	t := Tokens{{id: TidTPT}}
 	t = append(t, Token{id: TidS, val: e.V})
@@ -324,23 +438,50 @@ func (e Ln) Dx() Term {
 }
 
 func (e Ln) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if ok {
-		return S(val)
+		return S(math.Log(val))
+	}
+
+	// ln(e^p) = p exactly
+	switch a := e.X.(type) {
+	case EulerE:
+		return S(1)
+	case TP:
+		if _, ok := a.X.(EulerE); ok {
+			return S(a.P)
+		}
+	case TPT:
+		if _, ok := a.A.(EulerE); ok {
+			return a.B
+		}
+	case Exp:
+		return a.X
 	}
 
-	return Ln{e.X.T()}
+	return Ln{e.X}
 }
 
 func (e Ln) Is() (bool, float64) {
 	ok, val := e.X.Is()
 	if ok {
-		return true, val
+		return true, math.Log(val)
 	}
 
 	return false, 0
 }
 
+func (e Ln) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Ln) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Ln) Tokenise() Tokens {
 	t := Tokens{{id: TidLn}}
 	t = append(t, e.X.Tokenise()...)
@@ -0,0 +1,256 @@
+package alg
+
+import (
+	"errors"
+	"math"
+)
+
+/*
+Approx defines cheap approximations of a term over an interval:
+  Chebyshev => A polynomial interpolating the term at the Chebyshev nodes
+  Pade      => A rational function P(x)/Q(x) matching the taylor series at the centre of the interval
+Both return plain terms (built from S, Sx, X, Add, Mul and Div), so they can be tokenised and evaluated like any other.
+*/
+
+// Approximant is an approximation of a term over an interval, along with the worst error found.
+type Approximant struct {
+	Term   Term
+	MaxErr float64
+	ArgMax float64
+}
+
+// errSamples is the number of points the error of an approximant is sampled at.
+const errSamples = 1024
+
+// Chebyshev fits a polynomial of degree n to term on the interval [a, b].
+func Chebyshev(term Term, a, b float64, n int) (Approximant, error) {
+	if n < 0 {
+		return Approximant{}, errors.New("degree must not be negative")
+	}
+
+	if !(a < b) {
+		return Approximant{}, errors.New("invalid interval")
+	}
+
+	mid, half := (a+b)/2, (b-a)/2
+	nodes := n + 1
+
+	f := make([]float64, nodes)
+	for j := range f {
+		f[j] = term.E(mid + half*math.Cos(math.Pi*(float64(j)+0.5)/float64(nodes)))
+
+		if math.IsNaN(f[j]) || math.IsInf(f[j], 0) {
+			return Approximant{}, errors.New("term is not finite on the interval")
+		}
+	}
+
+	// p holds the monomial coefficients in u, where u maps [a, b] onto [-1, 1]
+	p := make([]float64, nodes)
+	tPrev, tCurr := []float64{1}, []float64{0, 1}
+
+	for k := 0; k < nodes; k++ {
+		var c float64
+		for j := range f {
+			c += f[j] * math.Cos(math.Pi*float64(k)*(float64(j)+0.5)/float64(nodes))
+		}
+		c *= 2 / float64(nodes)
+
+		if k == 0 {
+			c /= 2
+		}
+
+		tk := tPrev
+		if k > 0 {
+			tk = tCurr
+		}
+
+		for i, v := range tk {
+			p[i] += c * v
+		}
+
+		if k > 0 {
+			// T(k+1) = 2u T(k) - T(k-1)
+			next := make([]float64, len(tCurr)+1)
+			for i, v := range tCurr {
+				next[i+1] += 2 * v
+			}
+			for i, v := range tPrev {
+				next[i] -= v
+			}
+
+			tPrev, tCurr = tCurr, next
+		}
+	}
+
+	var u Term = Sx{1 / half}
+	if mid != 0 {
+		u = Add{Sx{1 / half}, S(-mid / half)}
+	}
+
+	approx := horner(p, u)
+	maxErr, argMax := maxError(term, approx, a, b)
+
+	return Approximant{
+		Term:   approx,
+		MaxErr: maxErr,
+		ArgMax: argMax,
+	}, nil
+}
+
+// Pade fits a rational function with a numerator of degree m and a denominator of degree n to term on the interval [a, b].
+// The taylor series of term is found by repeatedly differentiating it at the centre of the interval.
+func Pade(term Term, a, b float64, m, n int) (Approximant, error) {
+	if m < 0 || n < 0 {
+		return Approximant{}, errors.New("degree must not be negative")
+	}
+
+	if !(a < b) {
+		return Approximant{}, errors.New("invalid interval")
+	}
+
+	x0 := (a + b) / 2
+
+	c := make([]float64, m+n+1)
+	cache := NewDerivativeCache()
+	d := term
+	fact := 1.0
+	for k := range c {
+		if k > 0 {
+			fact *= float64(k)
+			d = cache.Dx(d)
+		}
+
+		c[k] = d.E(x0) / fact
+
+		if math.IsNaN(c[k]) || math.IsInf(c[k], 0) {
+			return Approximant{}, errors.New("term is not differentiable at the centre of the interval")
+		}
+	}
+
+	coef := func(i int) float64 {
+		if i < 0 {
+			return 0
+		}
+		return c[i]
+	}
+
+	// Solve sum(j=1..n) q[j] c[m+i-j] = -c[m+i] for i = 1..n
+	mat := make([][]float64, n)
+	rhs := make([]float64, n)
+	for i := range mat {
+		mat[i] = make([]float64, n)
+		for j := range mat[i] {
+			mat[i][j] = coef(m + i - j)
+		}
+		rhs[i] = -coef(m + i + 1)
+	}
+
+	sol, err := solveLinear(mat, rhs)
+	if err != nil {
+		return Approximant{}, err
+	}
+
+	q := append([]float64{1}, sol...)
+
+	p := make([]float64, m+1)
+	for i := range p {
+		for j := 0; j <= i && j <= n; j++ {
+			p[i] += q[j] * c[i-j]
+		}
+	}
+
+	var u Term = X{}
+	if x0 != 0 {
+		u = Add{X{}, S(-x0)}
+	}
+
+	var approx Term = horner(p, u)
+	if n > 0 {
+		approx = Div{
+			N: approx,
+			D: horner(q, u),
+		}
+	}
+
+	maxErr, argMax := maxError(term, approx, a, b)
+
+	return Approximant{
+		Term:   approx,
+		MaxErr: maxErr,
+		ArgMax: argMax,
+	}, nil
+}
+
+// horner builds the polynomial with coefficients p (lowest order first) in u using horner's method
+func horner(p []float64, u Term) Term {
+	var t Term = S(p[len(p)-1])
+
+	for i := len(p) - 2; i >= 0; i-- {
+		t = Add{
+			A: S(p[i]),
+			B: Mul{u, t},
+		}
+	}
+
+	return t
+}
+
+// maxError samples the absolute error between term and approx over [a, b]
+func maxError(term, approx Term, a, b float64) (float64, float64) {
+	var maxErr, argMax float64
+
+	for i := 0; i <= errSamples; i++ {
+		x := a + (b-a)*float64(i)/errSamples
+
+		err := math.Abs(term.E(x) - approx.E(x))
+		if math.IsNaN(err) {
+			err = math.Inf(1)
+		}
+
+		if err > maxErr || i == 0 {
+			maxErr, argMax = err, x
+		}
+	}
+
+	return maxErr, argMax
+}
+
+// solveLinear solves the system mat * v = rhs by gaussian elimination with partial pivoting
+func solveLinear(mat [][]float64, rhs []float64) ([]float64, error) {
+	n := len(rhs)
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(mat[row][col]) > math.Abs(mat[pivot][col]) {
+				pivot = row
+			}
+		}
+
+		if mat[pivot][col] == 0 {
+			return nil, errors.New("singular system")
+		}
+
+		mat[col], mat[pivot] = mat[pivot], mat[col]
+		rhs[col], rhs[pivot] = rhs[pivot], rhs[col]
+
+		for row := col + 1; row < n; row++ {
+			f := mat[row][col] / mat[col][col]
+			for k := col; k < n; k++ {
+				mat[row][k] -= f * mat[col][k]
+			}
+			rhs[row] -= f * rhs[col]
+		}
+	}
+
+	v := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		s := rhs[row]
+		for k := row + 1; k < n; k++ {
+			s -= mat[row][k] * v[k]
+		}
+		v[row] = s / mat[row][row]
+	}
+
+	return v, nil
+}
@@ -0,0 +1,390 @@
+package alg
+
+import "math"
+
+/*
+ArcHyperbolic defines the inverse hyperbolic functions:
+  Asinh => Inverse hyperbolic sine
+  Acosh => Inverse hyperbolic cosine, the non negative branch
+  Atanh => Inverse hyperbolic tangent
+  Asech => Inverse hyperbolic secant, acosh(1/x)
+  Acsch => Inverse hyperbolic cosecant, asinh(1/x)
+  Acoth => Inverse hyperbolic cotangent, atanh(1/x)
+*/
+
+func init() {
+	register(TidAsinh, Registration{
+		Name:  "asinh",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Asinh{X: args[0]}, nil
+		},
+		Type: Asinh{},
+	})
+	register(TidAcosh, Registration{
+		Name:  "acosh",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Acosh{X: args[0]}, nil
+		},
+		Type: Acosh{},
+	})
+	register(TidAtanh, Registration{
+		Name:  "atanh",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Atanh{X: args[0]}, nil
+		},
+		Type: Atanh{},
+	})
+	register(TidAsech, Registration{
+		Name:  "asech",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Asech{X: args[0]}, nil
+		},
+		Type: Asech{},
+	})
+	register(TidAcsch, Registration{
+		Name:  "acsch",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Acsch{X: args[0]}, nil
+		},
+		Type: Acsch{},
+	})
+	register(TidAcoth, Registration{
+		Name:  "acoth",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Acoth{X: args[0]}, nil
+		},
+		Type: Acoth{},
+	})
+}
+
+type Asinh struct {
+	X Term
+}
+
+func (e Asinh) Tokenise() Tokens {
+	t := Tokens{{id: TidAsinh}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Asinh) E(x float64) float64 {
+	return math.Asinh(e.X.E(x))
+}
+
+func (e Asinh) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		TP{Add{TP{e.X, 2}, S(1)}, -0.5},
+	}.T()
+}
+
+func (e Asinh) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Asinh(val))
+	}
+
+	// asinh(sinh(x)) = x for every x
+	if inv, ok := e.X.(Sinh); ok {
+		return inv.X.T()
+	}
+
+	return Asinh{e.X}
+}
+
+func (e Asinh) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Asinh(val)
+}
+
+func (e Asinh) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Asinh) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Acosh struct {
+	X Term
+}
+
+func (e Acosh) Tokenise() Tokens {
+	t := Tokens{{id: TidAcosh}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Acosh) E(x float64) float64 {
+	return math.Acosh(e.X.E(x))
+}
+
+func (e Acosh) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		TP{Sub{TP{e.X, 2}, S(1)}, -0.5},
+	}.T()
+}
+
+func (e Acosh) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Acosh(val))
+	}
+
+	return Acosh{e.X}
+}
+
+func (e Acosh) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Acosh(val)
+}
+
+func (e Acosh) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Acosh) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Atanh struct {
+	X Term
+}
+
+func (e Atanh) Tokenise() Tokens {
+	t := Tokens{{id: TidAtanh}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Atanh) E(x float64) float64 {
+	return math.Atanh(e.X.E(x))
+}
+
+func (e Atanh) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		TP{Sub{S(1), TP{e.X, 2}}, -1},
+	}.T()
+}
+
+func (e Atanh) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Atanh(val))
+	}
+
+	// atanh(tanh(x)) = x for every x
+	if inv, ok := e.X.(Tanh); ok {
+		return inv.X.T()
+	}
+
+	return Atanh{e.X}
+}
+
+func (e Atanh) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Atanh(val)
+}
+
+func (e Atanh) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Atanh) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Asech struct {
+	X Term
+}
+
+func (e Asech) Tokenise() Tokens {
+	t := Tokens{{id: TidAsech}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Asech) E(x float64) float64 {
+	return asech(e.X.E(x))
+}
+
+func (e Asech) Dx() Term {
+	return Prod{
+		S(-1),
+		e.X.Dx(),
+		TP{e.X, -1},
+		TP{Sub{S(1), TP{e.X, 2}}, -0.5},
+	}.T()
+}
+
+func (e Asech) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(asech(val))
+	}
+
+	return Asech{e.X}
+}
+
+func (e Asech) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, asech(val)
+}
+
+func (e Asech) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Asech) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Acsch struct {
+	X Term
+}
+
+func (e Acsch) Tokenise() Tokens {
+	t := Tokens{{id: TidAcsch}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Acsch) E(x float64) float64 {
+	return acsch(e.X.E(x))
+}
+
+func (e Acsch) Dx() Term {
+	return Prod{
+		S(-1),
+		e.X.Dx(),
+		TP{TP{e.X, 2}, -0.5},
+		TP{Add{TP{e.X, 2}, S(1)}, -0.5},
+	}.T()
+}
+
+func (e Acsch) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(acsch(val))
+	}
+
+	return Acsch{e.X}
+}
+
+func (e Acsch) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, acsch(val)
+}
+
+func (e Acsch) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Acsch) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Acoth struct {
+	X Term
+}
+
+func (e Acoth) Tokenise() Tokens {
+	t := Tokens{{id: TidAcoth}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Acoth) E(x float64) float64 {
+	return acoth(e.X.E(x))
+}
+
+func (e Acoth) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		TP{Sub{S(1), TP{e.X, 2}}, -1},
+	}.T()
+}
+
+func (e Acoth) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(acoth(val))
+	}
+
+	return Acoth{e.X}
+}
+
+func (e Acoth) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, acoth(val)
+}
+
+func (e Acoth) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Acoth) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+func asech(v float64) float64 {
+	return math.Acosh(1 / v)
+}
+
+func acsch(v float64) float64 {
+	return math.Asinh(1 / v)
+}
+
+func acoth(v float64) float64 {
+	return math.Atanh(1 / v)
+}
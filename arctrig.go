@@ -0,0 +1,453 @@
+package alg
+
+import (
+	"math"
+)
+
+/*
+ArcTrig defines the inverse trigonometric functions (all angles are radians):
+  Asin  => Inverse sine, in [-pi/2, pi/2]
+  Acos  => Inverse cosine, in [0, pi]
+  Atan  => Inverse tangent, in (-pi/2, pi/2)
+  Asec  => Inverse secant, acos(1/x)
+  Acsc  => Inverse cosecant, asin(1/x)
+  Acot  => Inverse cotangent, pi/2 - atan(x), in (0, pi)
+  Atan2 => The angle of the point (X, Y), in [-pi, pi]
+*/
+
+func init() {
+	register(TidAsin, Registration{
+		Name:  "asin",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Asin{X: args[0]}, nil
+		},
+		Type: Asin{},
+	})
+	register(TidAcos, Registration{
+		Name:  "acos",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Acos{X: args[0]}, nil
+		},
+		Type: Acos{},
+	})
+	register(TidAtan, Registration{
+		Name:  "atan",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Atan{X: args[0]}, nil
+		},
+		Type: Atan{},
+	})
+	register(TidAsec, Registration{
+		Name:  "asec",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Asec{X: args[0]}, nil
+		},
+		Type: Asec{},
+	})
+	register(TidAcsc, Registration{
+		Name:  "acsc",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Acsc{X: args[0]}, nil
+		},
+		Type: Acsc{},
+	})
+	register(TidAcot, Registration{
+		Name:  "acot",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Acot{X: args[0]}, nil
+		},
+		Type: Acot{},
+	})
+	register(TidAtan2, Registration{
+		Name:  "atan2",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Atan2{Y: args[0], X: args[1]}, nil
+		},
+		Type: Atan2{},
+	})
+}
+
+type Asin struct {
+	X Term
+}
+
+func (e Asin) Tokenise() Tokens {
+	t := Tokens{{id: TidAsin}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Asin) E(x float64) float64 {
+	return math.Asin(e.X.E(x))
+}
+
+func (e Asin) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		TP{Sub{S(1), TP{e.X, 2}}, -0.5},
+	}.T()
+}
+
+func (e Asin) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Asin(val))
+	}
+
+	return Asin{e.X}
+}
+
+func (e Asin) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Asin(val)
+}
+
+func (e Asin) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Asin) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Acos struct {
+	X Term
+}
+
+func (e Acos) Tokenise() Tokens {
+	t := Tokens{{id: TidAcos}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Acos) E(x float64) float64 {
+	return math.Acos(e.X.E(x))
+}
+
+func (e Acos) Dx() Term {
+	return Prod{
+		S(-1),
+		e.X.Dx(),
+		TP{Sub{S(1), TP{e.X, 2}}, -0.5},
+	}.T()
+}
+
+func (e Acos) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Acos(val))
+	}
+
+	return Acos{e.X}
+}
+
+func (e Acos) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Acos(val)
+}
+
+func (e Acos) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Acos) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Atan struct {
+	X Term
+}
+
+func (e Atan) Tokenise() Tokens {
+	t := Tokens{{id: TidAtan}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Atan) E(x float64) float64 {
+	return math.Atan(e.X.E(x))
+}
+
+func (e Atan) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		TP{Add{S(1), TP{e.X, 2}}, -1},
+	}.T()
+}
+
+func (e Atan) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Atan(val))
+	}
+
+	return Atan{e.X}
+}
+
+func (e Atan) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Atan(val)
+}
+
+func (e Atan) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Atan) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Asec struct {
+	X Term
+}
+
+func (e Asec) Tokenise() Tokens {
+	t := Tokens{{id: TidAsec}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Asec) E(x float64) float64 {
+	return asec(e.X.E(x))
+}
+
+func (e Asec) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		TP{TP{e.X, 2}, -0.5},
+		TP{Sub{TP{e.X, 2}, S(1)}, -0.5},
+	}.T()
+}
+
+func (e Asec) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(asec(val))
+	}
+
+	return Asec{e.X}
+}
+
+func (e Asec) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, asec(val)
+}
+
+func (e Asec) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Asec) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Acsc struct {
+	X Term
+}
+
+func (e Acsc) Tokenise() Tokens {
+	t := Tokens{{id: TidAcsc}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Acsc) E(x float64) float64 {
+	return acsc(e.X.E(x))
+}
+
+func (e Acsc) Dx() Term {
+	return Prod{
+		S(-1),
+		e.X.Dx(),
+		TP{TP{e.X, 2}, -0.5},
+		TP{Sub{TP{e.X, 2}, S(1)}, -0.5},
+	}.T()
+}
+
+func (e Acsc) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(acsc(val))
+	}
+
+	return Acsc{e.X}
+}
+
+func (e Acsc) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, acsc(val)
+}
+
+func (e Acsc) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Acsc) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Acot struct {
+	X Term
+}
+
+func (e Acot) Tokenise() Tokens {
+	t := Tokens{{id: TidAcot}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Acot) E(x float64) float64 {
+	return acot(e.X.E(x))
+}
+
+func (e Acot) Dx() Term {
+	return Prod{
+		S(-1),
+		e.X.Dx(),
+		TP{Add{S(1), TP{e.X, 2}}, -1},
+	}.T()
+}
+
+func (e Acot) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(acot(val))
+	}
+
+	return Acot{e.X}
+}
+
+func (e Acot) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, acot(val)
+}
+
+func (e Acot) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Acot) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Atan2 struct {
+	Y, X Term
+}
+
+func (e Atan2) Tokenise() Tokens {
+	t := Tokens{{id: TidAtan2}}
+	t = append(t, e.Y.Tokenise()...)
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Atan2) E(x float64) float64 {
+	return math.Atan2(e.Y.E(x), e.X.E(x))
+}
+
+func (e Atan2) Dx() Term {
+	return Div{
+		N: Sub{
+			Mul{e.X, e.Y.Dx()},
+			Mul{e.Y, e.X.Dx()},
+		},
+		D: Add{
+			TP{e.X, 2},
+			TP{e.Y, 2},
+		},
+	}.T()
+}
+
+func (e Atan2) T() Term {
+	e = simplified(e)
+
+	yok, yv := e.Y.Is()
+	xok, xv := e.X.Is()
+
+	if yok && xok {
+		return S(math.Atan2(yv, xv))
+	}
+
+	return Atan2{e.Y, e.X}
+}
+
+func (e Atan2) Is() (bool, float64) {
+	yok, yv := e.Y.Is()
+	xok, xv := e.X.Is()
+
+	if !yok || !xok {
+		return false, 0
+	}
+
+	return true, math.Atan2(yv, xv)
+}
+
+func (e Atan2) Children() []Term {
+	return []Term{e.Y, e.X}
+}
+
+func (e Atan2) WithChildren(children []Term) Term {
+	e.Y, e.X = children[0], children[1]
+	return e
+}
+
+func asec(v float64) float64 {
+	return math.Acos(1 / v)
+}
+
+func acsc(v float64) float64 {
+	return math.Asin(1 / v)
+}
+
+func acot(v float64) float64 {
+	return math.Pi/2 - math.Atan(v)
+}
@@ -10,6 +10,65 @@ Arithmetic defines all the simple arithmetic datatypes:
   Mul  => Multiply two terms
 */
 
+func init() {
+	register(TidSumEn, Registration{
+		Name: "]+",
+	})
+	register(TidSumSt, Registration{
+		Name:  "+[",
+		Arity: Bracketed,
+		Close: TidSumEn,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sum(args), nil
+		},
+		Type: Sum{},
+	})
+	register(TidProdEn, Registration{
+		Name: "]*",
+	})
+	register(TidProdSt, Registration{
+		Name:  "*[",
+		Arity: Bracketed,
+		Close: TidProdEn,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Prod(args), nil
+		},
+		Type: Prod{},
+	})
+	register(TidDiv, Registration{
+		Name:  "/",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Div{N: args[0], D: args[1]}, nil
+		},
+		Type: Div{},
+	})
+	register(TidAdd, Registration{
+		Name:  "+",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Add{A: args[0], B: args[1]}, nil
+		},
+		Type: Add{},
+	})
+	register(TidSub, Registration{
+		Name:  "-",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sub{A: args[0], B: args[1]}, nil
+		},
+		Type: Sub{},
+	})
+	register(TidMul, Registration{
+		Name:  "*",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Mul{A: args[0], B: args[1]}, nil
+		},
+		Type: Mul{},
+	})
+}
+
 type Sum []Term
 
 func (e Sum) Tokenise() Tokens {
@@ -43,6 +102,8 @@ func (e Sum) Dx() Term {
 }
 
 func (e Sum) T() Term {
+	e = simplified(e)
+
 	flat := e.flatten()
 
 	sum := make(Sum, 0, len(flat))
@@ -53,7 +114,7 @@ func (e Sum) T() Term {
 		if ok {
 			total += val
 		} else {
-			sum = append(sum, term.T())
+			sum = append(sum, term)
 		}
 	}
 
@@ -66,7 +127,7 @@ func (e Sum) T() Term {
 	}
 
 	if len(sum) == 1 {
-		return sum[0].T()
+		return sum[0]
 	} else if len(sum) == 2 {
 		return Add{
 			A: sum[0],
@@ -91,6 +152,14 @@ func (e Sum) Is() (bool, float64) {
 	return true, cs
 }
 
+func (e Sum) Children() []Term {
+	return append([]Term(nil), e...)
+}
+
+func (e Sum) WithChildren(children []Term) Term {
+	return append(Sum(nil), children...)
+}
+
 func (e Sum) flatten() Sum {
 	s1 := make(Sum, len(e))
 
@@ -157,6 +226,7 @@ func (e Prod) Dx() Term {
 			}
 
 			prod[count] = e[j]
+			count++
 		}
 
 		sum[i] = prod.T()
@@ -166,6 +236,8 @@ func (e Prod) Dx() Term {
 }
 
 func (e Prod) T() Term {
+	e = simplified(e)
+
 	flat := e.flatten()
 
 	prod := make(Prod, 0, len(e))
@@ -176,7 +248,7 @@ func (e Prod) T() Term {
 		if ok {
 			total *= val
 		} else {
-			prod = append(prod, term.T())
+			prod = append(prod, term)
 		}
 	}
 
@@ -193,7 +265,7 @@ func (e Prod) T() Term {
 	}
 
 	if len(prod) == 1 {
-		return prod[0].T()
+		return prod[0]
 	} else if len(prod) == 2 {
 		return Mul{
 			A: prod[0],
@@ -228,6 +300,14 @@ func (e Prod) Is() (bool, float64) {
 	return true, sc
 }
 
+func (e Prod) Children() []Term {
+	return append([]Term(nil), e...)
+}
+
+func (e Prod) WithChildren(children []Term) Term {
+	return append(Prod(nil), children...)
+}
+
 func (e Prod) flatten() Prod {
 	p1 := make(Prod, len(e))
 
@@ -239,7 +319,7 @@ func (e Prod) flatten() Prod {
 		p2 := make(Prod, 0)
 		changed = false
 		for _, term := range p1 {
-			if p, ok := term.(Sum); ok {
+			if p, ok := term.(Prod); ok {
 				p2 = append(p2, p...)
 				changed = true
 			} else if p, ok := term.(Mul); ok {
@@ -290,6 +370,8 @@ func (e Div) Dx() Term {
 }
 
 func (e Div) T() Term {
+	e = simplified(e)
+
 	nOk, nVal := e.N.Is()
 	dOk, dVal := e.D.Is()
 
@@ -302,8 +384,8 @@ func (e Div) T() Term {
 	}
 
 	return Div{
-		N: e.N.T(),
-		D: e.D.T(),
+		N: e.N,
+		D: e.D,
 	}
 }
 
@@ -318,6 +400,15 @@ func (e Div) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Div) Children() []Term {
+	return []Term{e.N, e.D}
+}
+
+func (e Div) WithChildren(children []Term) Term {
+	e.N, e.D = children[0], children[1]
+	return e
+}
+
 type Add struct {
 	A Term
 	B Term
@@ -335,6 +426,8 @@ func (e Add) Dx() Term {
 }
 
 func (e Add) T() Term {
+	e = simplified(e)
+
 	aok, av := e.A.Is()
 	bok, bv := e.B.Is()
 
@@ -346,7 +439,7 @@ func (e Add) T() Term {
 		return e.A
 	}
 
-	return Add{e.A.T(), e.B.T()}
+	return Add{e.A, e.B}
 }
 
 func (e Add) Is() (bool, float64) {
@@ -360,6 +453,15 @@ func (e Add) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Add) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e Add) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
 func (e Add) Tokenise() Tokens {
 	t := Tokens{{id: TidAdd}}
 	t = append(t, e.A.Tokenise()...)
@@ -384,6 +486,8 @@ func (e Sub) Dx() Term {
 }
 
 func (e Sub) T() Term {
+	e = simplified(e)
+
 	aok, av := e.A.Is()
 	bok, bv := e.B.Is()
 
@@ -395,7 +499,7 @@ func (e Sub) T() Term {
 		return e.A
 	}
 
-	return Sub{e.A.T(), e.B.T()}
+	return Sub{e.A, e.B}
 }
 
 func (e Sub) Is() (bool, float64) {
@@ -409,6 +513,15 @@ func (e Sub) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Sub) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e Sub) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
 func (e Sub) Tokenise() Tokens {
 	t := Tokens{{id: TidSub}}
 	t = append(t, e.A.Tokenise()...)
@@ -433,11 +546,13 @@ func (e Mul) Dx() Term {
 }
 
 func (e Mul) T() Term {
+	e = simplified(e)
+
 	aok, av := e.A.Is()
 	bok, bv := e.B.Is()
 
 	if aok && bok {
-		return S(av + bv)
+		return S(av * bv)
 	} else if aok && av == 1 {
 		return e.B
 	} else if bok && bv == 1 {
@@ -446,7 +561,7 @@ func (e Mul) T() Term {
 		return S(0)
 	}
 
-	return Mul{e.A.T(), e.B.T()}
+	return Mul{e.A, e.B}
 }
 
 func (e Mul) Is() (bool, float64) {
@@ -454,7 +569,7 @@ func (e Mul) Is() (bool, float64) {
 	bok, bv := e.B.Is()
 
 	if aok && bok {
-		return true, av + bv
+		return true, av * bv
 	} else if aok && av == 0 || bok && bv == 0 {
 		return true, 0
 	}
@@ -462,6 +577,15 @@ func (e Mul) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Mul) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e Mul) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
 func (e Mul) Tokenise() Tokens {
 	t := Tokens{{id: TidMul}}
 	t = append(t, e.A.Tokenise()...)
@@ -0,0 +1,432 @@
+package alg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The kinds of error that evaluation can fail with, wrapped by EvalError.
+// ErrDomain and ErrPole mean the input was bad, ErrOverflow means a value was too large to represent,
+// and ErrUndefined means the term calls a function that isn't defined.
+var (
+	ErrDomain    = errors.New("argument outside of domain")
+	ErrPole      = errors.New("evaluated at a pole")
+	ErrOverflow  = errors.New("value overflowed")
+	ErrUndefined = errors.New("function is not defined")
+)
+
+// poleEps is how close (relative to the argument) sin or cos has to be to 0 for the argument to be treated as a pole.
+// Poles like pi/2 can't be represented exactly, so tan never actually returns inf.
+const poleEps = 1e-15
+
+// EvalError reports the node where evaluation first went wrong.
+// Path holds the index of the child taken at each step from the root to the node, in the order the fields are declared.
+type EvalError struct {
+	Path   []int
+	Term   Term
+	Err    error
+	Reason string
+}
+
+func (e *EvalError) Error() string {
+	t := e.Term.Tokenise()
+	return fmt.Sprintf("%s at %v (%s): %s", e.Err, e.Path, t.String(), e.Reason)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// EvalChecked evaluates a term like E, but returns an error if the result is NaN or infinite.
+// The error is an *EvalError describing the first node to produce a non finite value from finite arguments, that the rest of the term didn't recover from.
+// Intermediate infinities that are recovered from, such as in 1 / (1 / 0), are not errors.
+func EvalChecked(term Term, x float64) (float64, error) {
+	return evalChecked(term, x, false)
+}
+
+// EvalStrict evaluates a term, stopping with an error as soon as any node produces a NaN or infinite value.
+func EvalStrict(term Term, x float64) (float64, error) {
+	return evalChecked(term, x, true)
+}
+
+func evalChecked(term Term, x float64, strict bool) (float64, error) {
+	c := checker{x: x, strict: strict}
+
+	v := c.eval(term, []int{})
+	if c.err != nil && (strict || !finite(v)) {
+		return v, c.err
+	}
+
+	return v, nil
+}
+
+// checker holds the state of a single checked evaluation
+type checker struct {
+	x      float64
+	strict bool
+	err    *EvalError
+}
+
+// eval evaluates a term, recording the first node that goes wrong
+func (c *checker) eval(term Term, path []int) float64 {
+	if c.strict && c.err != nil {
+		return math.NaN()
+	}
+
+	ok := true
+	arg := func(t Term, i int) float64 {
+		sub := make([]int, len(path), len(path)+1)
+		copy(sub, path)
+
+		v := c.eval(t, append(sub, i))
+		ok = ok && finite(v)
+		return v
+	}
+
+	var v float64
+
+	// kind and reason explain a non finite value, and default to overflow
+	kind, reason := ErrOverflow, "result is too large"
+
+	switch e := term.(type) {
+	case S:
+		v = float64(e)
+		reason = "constant is not finite"
+	case X:
+		v = c.x
+		if math.IsNaN(v) {
+			kind, reason = ErrDomain, "x is NaN"
+		} else {
+			reason = "x is infinite"
+		}
+	case Sx:
+		v = e.S * c.x
+	case Sum:
+		v = 0
+		for i, sub := range e {
+			v += arg(sub, i)
+		}
+	case Prod:
+		v = 1
+		for i, sub := range e {
+			v *= arg(sub, i)
+		}
+	case Add:
+		v = arg(e.A, 0) + arg(e.B, 1)
+	case Sub:
+		v = arg(e.A, 0) - arg(e.B, 1)
+	case Mul:
+		v = arg(e.A, 0) * arg(e.B, 1)
+	case Div:
+		n, d := arg(e.N, 0), arg(e.D, 1)
+		v = n / d
+		if d == 0 && n == 0 {
+			kind, reason = ErrDomain, "zero divided by zero"
+		} else if d == 0 {
+			kind, reason = ErrPole, "division by zero"
+		}
+	case Exp:
+		v = math.Exp(arg(e.X, 0))
+	case Ln:
+		a := arg(e.X, 0)
+		v = math.Log(a)
+		if a < 0 {
+			kind, reason = ErrDomain, "log of a negative number"
+		} else if a == 0 {
+			kind, reason = ErrPole, "log of zero"
+		}
+	case Log:
+		b, a := arg(e.Base, 0), arg(e.X, 1)
+		v = math.Log(a) / math.Log(b)
+		if a < 0 || b < 0 {
+			kind, reason = ErrDomain, "log of a negative number"
+		} else if a == 0 {
+			kind, reason = ErrPole, "log of zero"
+		} else if b == 1 {
+			kind, reason = ErrPole, "log to the base 1"
+		}
+	case Log2:
+		a := arg(e.X, 0)
+		v = math.Log2(a)
+		if a < 0 {
+			kind, reason = ErrDomain, "log of a negative number"
+		} else if a == 0 {
+			kind, reason = ErrPole, "log of zero"
+		}
+	case Log10:
+		a := arg(e.X, 0)
+		v = math.Log10(a)
+		if a < 0 {
+			kind, reason = ErrDomain, "log of a negative number"
+		} else if a == 0 {
+			kind, reason = ErrPole, "log of zero"
+		}
+	case Sqrt:
+		v = math.Sqrt(arg(e.X, 0))
+		kind, reason = ErrDomain, "square root of a negative number"
+	case Cbrt:
+		v = math.Cbrt(arg(e.X, 0))
+	case TP:
+		a := arg(e.X, 0)
+		v = math.Pow(a, e.P)
+		kind, reason = powReason(a, e.P)
+	case PT:
+		b := arg(e.X, 0)
+		v = math.Pow(e.V, b)
+		kind, reason = powReason(e.V, b)
+	case TPT:
+		a, b := arg(e.A, 0), arg(e.B, 1)
+		v = math.Pow(a, b)
+		kind, reason = powReason(a, b)
+	case Sin:
+		v = math.Sin(arg(e.X, 0))
+	case Cos:
+		v = math.Cos(arg(e.X, 0))
+	case Tan:
+		a := arg(e.X, 0)
+		v = poleAt(math.Tan(a), math.Cos(a), a)
+		kind, reason = ErrPole, "tan at a pole"
+	case Sec:
+		a := arg(e.X, 0)
+		v = poleAt(1/math.Cos(a), math.Cos(a), a)
+		kind, reason = ErrPole, "sec at a pole"
+	case Csc:
+		a := arg(e.X, 0)
+		v = poleAt(1/math.Sin(a), math.Sin(a), a)
+		kind, reason = ErrPole, "csc at a pole"
+	case Cot:
+		a := arg(e.X, 0)
+		v = poleAt(1/math.Tan(a), math.Sin(a), a)
+		kind, reason = ErrPole, "cot at a pole"
+	case Sinh:
+		v = math.Sinh(arg(e.X, 0))
+	case Cosh:
+		v = math.Cosh(arg(e.X, 0))
+	case Tanh:
+		v = math.Tanh(arg(e.X, 0))
+	case Sech:
+		v = 1 / math.Cosh(arg(e.X, 0))
+	case Csch:
+		v = 1 / math.Sinh(arg(e.X, 0))
+		kind, reason = ErrPole, "csch of zero"
+	case Coth:
+		v = 1 / math.Tanh(arg(e.X, 0))
+		kind, reason = ErrPole, "coth of zero"
+	case Asin:
+		v = math.Asin(arg(e.X, 0))
+		kind, reason = ErrDomain, "asin of a number outside [-1, 1]"
+	case Acos:
+		v = math.Acos(arg(e.X, 0))
+		kind, reason = ErrDomain, "acos of a number outside [-1, 1]"
+	case Atan:
+		v = math.Atan(arg(e.X, 0))
+	case Asec:
+		v = asec(arg(e.X, 0))
+		kind, reason = ErrDomain, "asec of a number inside (-1, 1)"
+	case Acsc:
+		v = acsc(arg(e.X, 0))
+		kind, reason = ErrDomain, "acsc of a number inside (-1, 1)"
+	case Acot:
+		v = acot(arg(e.X, 0))
+	case Atan2:
+		v = math.Atan2(arg(e.Y, 0), arg(e.X, 1))
+	case Asinh:
+		v = math.Asinh(arg(e.X, 0))
+	case Acosh:
+		v = math.Acosh(arg(e.X, 0))
+		kind, reason = ErrDomain, "acosh of a number less than 1"
+	case Atanh:
+		a := arg(e.X, 0)
+		v = math.Atanh(a)
+		if math.Abs(a) == 1 {
+			kind, reason = ErrPole, "atanh of 1 or -1"
+		} else {
+			kind, reason = ErrDomain, "atanh of a number outside (-1, 1)"
+		}
+	case Asech:
+		a := arg(e.X, 0)
+		v = asech(a)
+		if a == 0 {
+			kind, reason = ErrPole, "asech of zero"
+		} else {
+			kind, reason = ErrDomain, "asech of a number outside (0, 1]"
+		}
+	case Acsch:
+		v = acsch(arg(e.X, 0))
+		kind, reason = ErrPole, "acsch of zero"
+	case Acoth:
+		a := arg(e.X, 0)
+		v = acoth(a)
+		if math.Abs(a) == 1 {
+			kind, reason = ErrPole, "acoth of 1 or -1"
+		} else {
+			kind, reason = ErrDomain, "acoth of a number inside [-1, 1]"
+		}
+	case Abs:
+		v = math.Abs(arg(e.X, 0))
+	case Sign:
+		v = sgn(arg(e.X, 0))
+	case Min:
+		v = math.Min(arg(e.A, 0), arg(e.B, 1))
+	case Max:
+		v = math.Max(arg(e.A, 0), arg(e.B, 1))
+	case Floor:
+		v = math.Floor(arg(e.X, 0))
+	case Ceil:
+		v = math.Ceil(arg(e.X, 0))
+	case Mod:
+		v = mod(arg(e.A, 0), arg(e.B, 1))
+		kind, reason = ErrDomain, "mod by zero"
+	case Erf:
+		v = math.Erf(arg(e.X, 0))
+	case Erfc:
+		v = math.Erfc(arg(e.X, 0))
+	case Gamma:
+		v = gamma(arg(e.X, 0))
+		kind, reason = ErrPole, "gamma at a non positive integer"
+	case LogGamma:
+		v = lgamma(arg(e.X, 0))
+		kind, reason = ErrPole, "log gamma at a non positive integer"
+	case Digamma:
+		v = polygamma(0, arg(e.X, 0))
+		kind, reason = ErrPole, "digamma at a non positive integer"
+	case Polygamma:
+		v = polygamma(e.N, arg(e.X, 0))
+		if e.N < 0 {
+			kind, reason = ErrDomain, "polygamma of negative order"
+		} else {
+			kind, reason = ErrPole, "polygamma at a non positive integer"
+		}
+	case Beta:
+		v = beta(arg(e.A, 0), arg(e.B, 1))
+		kind, reason = ErrPole, "beta of a non positive integer"
+	case BesselJ:
+		v = math.Jn(e.N, arg(e.X, 0))
+	case BesselY:
+		a := arg(e.X, 0)
+		v = math.Yn(e.N, a)
+		if a == 0 {
+			kind, reason = ErrPole, "bessel y of zero"
+		} else {
+			kind, reason = ErrDomain, "bessel y of a negative number"
+		}
+	case Greater:
+		if arg(e.A, 0) > arg(e.B, 1) {
+			v = arg(e.If, 2)
+		} else {
+			v = arg(e.Else, 3)
+		}
+	case Less:
+		if arg(e.A, 0) < arg(e.B, 1) {
+			v = arg(e.If, 2)
+		} else {
+			v = arg(e.Else, 3)
+		}
+	case GreaterEqual:
+		if arg(e.A, 0) >= arg(e.B, 1) {
+			v = arg(e.If, 2)
+		} else {
+			v = arg(e.Else, 3)
+		}
+	case LessEqual:
+		if arg(e.A, 0) <= arg(e.B, 1) {
+			v = arg(e.If, 2)
+		} else {
+			v = arg(e.Else, 3)
+		}
+	case Equal:
+		if arg(e.A, 0) == arg(e.B, 1) {
+			v = arg(e.If, 2)
+		} else {
+			v = arg(e.Else, 3)
+		}
+	case NotEqual:
+		if arg(e.A, 0) != arg(e.B, 1) {
+			v = arg(e.If, 2)
+		} else {
+			v = arg(e.Else, 3)
+		}
+	case Call:
+		a := arg(e.X, 0)
+		if f, defined := lookupFunction(e.Name); defined {
+			v = f.Body.E(a)
+		} else {
+			v = math.NaN()
+			kind, reason = ErrUndefined, fmt.Sprintf("%q is not defined", e.Name)
+		}
+	case Range:
+		x := arg(e.X, 0)
+		if x >= arg(e.A, 1) && x <= arg(e.B, 2) {
+			v = arg(e.If, 3)
+		} else {
+			v = arg(e.Else, 4)
+		}
+	default:
+		v = term.E(c.x)
+		if math.IsNaN(v) {
+			kind, reason = ErrDomain, "result is NaN"
+		}
+	}
+
+	// Only blame this node if its arguments were fine, so that the error points to where the problem started
+	if ok && !finite(v) && c.err == nil {
+		if math.IsNaN(v) && kind == ErrOverflow {
+			kind, reason = ErrDomain, "result is NaN"
+		}
+
+		c.err = &EvalError{
+			Path:   path,
+			Term:   term,
+			Err:    kind,
+			Reason: reason,
+		}
+	}
+
+	// Forget problems this node recovered from, unless failing fast
+	if !c.strict && finite(v) && c.err != nil && within(c.err.Path, path) {
+		c.err = nil
+	}
+
+	return v
+}
+
+// powReason explains why a to the power of b is not finite
+func powReason(a, b float64) (error, string) {
+	if a < 0 && b != math.Trunc(b) {
+		return ErrDomain, "fractional power of a negative number"
+	} else if a == 0 && b < 0 {
+		return ErrPole, "negative power of zero"
+	}
+
+	return ErrOverflow, "result is too large"
+}
+
+// poleAt returns an infinity in place of v if the function it came from has a pole at a, which is when d is zero
+func poleAt(v, d, a float64) float64 {
+	if math.Abs(d) <= poleEps*math.Abs(a) || d == 0 {
+		return math.Inf(sign(v))
+	}
+
+	return v
+}
+
+// within returns true if path leads to a node inside the subtree at root
+func within(path, root []int) bool {
+	if len(path) < len(root) {
+		return false
+	}
+
+	for i := range root {
+		if path[i] != root[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// finite returns true if v is neither NaN nor infinite
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
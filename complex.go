@@ -0,0 +1,567 @@
+package alg
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// EvalComplex evaluates a term at a complex x, taking the principal value of logarithms and powers.
+// This gives values where E returns NaN, such as the log of a negative number or a fractional power of one.
+//
+// Comparisons have no natural meaning for complex numbers, so conditional terms compare the real parts of their arguments.
+// Equal and NotEqual are the exception, and compare the whole complex value.
+// Abs is the modulus and Sign is z / |z|, Min and Max also compare real parts, and Floor, Ceil and Mod round the real and imaginary parts separately.
+// Special functions take their principal values, with LogGamma continued from the positive real axis with a branch cut along the negative one,
+// and BesselY cut along the negative real axis like Ln.
+// Terms this doesn't know about are evaluated with E on the real axis, and are NaN elsewhere.
+func EvalComplex(term Term, z complex128) complex128 {
+	switch e := term.(type) {
+	case S:
+		return complex(float64(e), 0)
+	case X:
+		return z
+	case Sx:
+		return complex(e.S, 0) * z
+	case Pi, EulerE, Phi:
+		return complex(term.E(0), 0)
+	case Sum:
+		var out complex128
+		for _, sub := range e {
+			out += EvalComplex(sub, z)
+		}
+		return out
+	case Prod:
+		var out complex128 = 1
+		for _, sub := range e {
+			out *= EvalComplex(sub, z)
+		}
+		return out
+	case Add:
+		return EvalComplex(e.A, z) + EvalComplex(e.B, z)
+	case Sub:
+		return EvalComplex(e.A, z) - EvalComplex(e.B, z)
+	case Mul:
+		return EvalComplex(e.A, z) * EvalComplex(e.B, z)
+	case Div:
+		return EvalComplex(e.N, z) / EvalComplex(e.D, z)
+	case Exp:
+		return cmplx.Exp(EvalComplex(e.X, z))
+	case Ln:
+		return cmplx.Log(EvalComplex(e.X, z))
+	case Log:
+		return cmplx.Log(EvalComplex(e.X, z)) / cmplx.Log(EvalComplex(e.Base, z))
+	case Log2:
+		return cmplx.Log(EvalComplex(e.X, z)) / math.Ln2
+	case Log10:
+		return cmplx.Log10(EvalComplex(e.X, z))
+	case Sqrt:
+		return cmplx.Sqrt(EvalComplex(e.X, z))
+	case Cbrt:
+		v := EvalComplex(e.X, z)
+		if imag(v) == 0 {
+			return complex(math.Cbrt(real(v)), 0)
+		}
+		return cmplx.Pow(v, 1.0/3)
+	case TPT:
+		return powC(EvalComplex(e.A, z), EvalComplex(e.B, z))
+	case TP:
+		return powC(EvalComplex(e.X, z), complex(e.P, 0))
+	case PT:
+		return powC(complex(e.V, 0), EvalComplex(e.X, z))
+	case Sin:
+		return cmplx.Sin(EvalComplex(e.X, z))
+	case Cos:
+		return cmplx.Cos(EvalComplex(e.X, z))
+	case Tan:
+		return cmplx.Tan(EvalComplex(e.X, z))
+	case Sec:
+		return 1 / cmplx.Cos(EvalComplex(e.X, z))
+	case Csc:
+		return 1 / cmplx.Sin(EvalComplex(e.X, z))
+	case Cot:
+		return cmplx.Cot(EvalComplex(e.X, z))
+	case Sinh:
+		return cmplx.Sinh(EvalComplex(e.X, z))
+	case Cosh:
+		return cmplx.Cosh(EvalComplex(e.X, z))
+	case Tanh:
+		return cmplx.Tanh(EvalComplex(e.X, z))
+	case Sech:
+		return 1 / cmplx.Cosh(EvalComplex(e.X, z))
+	case Csch:
+		return 1 / cmplx.Sinh(EvalComplex(e.X, z))
+	case Coth:
+		return 1 / cmplx.Tanh(EvalComplex(e.X, z))
+	case Asin:
+		return cmplx.Asin(EvalComplex(e.X, z))
+	case Acos:
+		return cmplx.Acos(EvalComplex(e.X, z))
+	case Atan:
+		return cmplx.Atan(EvalComplex(e.X, z))
+	case Asec:
+		return cmplx.Acos(1 / EvalComplex(e.X, z))
+	case Acsc:
+		return cmplx.Asin(1 / EvalComplex(e.X, z))
+	case Acot:
+		return math.Pi/2 - cmplx.Atan(EvalComplex(e.X, z))
+	case Atan2:
+		y, x := EvalComplex(e.Y, z), EvalComplex(e.X, z)
+		if imag(y) == 0 && imag(x) == 0 {
+			return complex(math.Atan2(real(y), real(x)), 0)
+		}
+
+		// The argument of x + iy, continued to complex x and y
+		return -1i * cmplx.Log((x+1i*y)/cmplx.Sqrt(x*x+y*y))
+	case Asinh:
+		return cmplx.Asinh(EvalComplex(e.X, z))
+	case Acosh:
+		return cmplx.Acosh(EvalComplex(e.X, z))
+	case Atanh:
+		return cmplx.Atanh(EvalComplex(e.X, z))
+	case Asech:
+		return cmplx.Acosh(1 / EvalComplex(e.X, z))
+	case Acsch:
+		return cmplx.Asinh(1 / EvalComplex(e.X, z))
+	case Acoth:
+		return cmplx.Atanh(1 / EvalComplex(e.X, z))
+	case Abs:
+		return complex(cmplx.Abs(EvalComplex(e.X, z)), 0)
+	case Sign:
+		v := EvalComplex(e.X, z)
+		if v == 0 {
+			return 0
+		}
+		return v / complex(cmplx.Abs(v), 0)
+	case Min:
+		a, b := EvalComplex(e.A, z), EvalComplex(e.B, z)
+		if real(b) < real(a) {
+			return b
+		}
+		return a
+	case Max:
+		a, b := EvalComplex(e.A, z), EvalComplex(e.B, z)
+		if real(b) > real(a) {
+			return b
+		}
+		return a
+	case Floor:
+		v := EvalComplex(e.X, z)
+		return complex(math.Floor(real(v)), math.Floor(imag(v)))
+	case Ceil:
+		v := EvalComplex(e.X, z)
+		return complex(math.Ceil(real(v)), math.Ceil(imag(v)))
+	case Mod:
+		a, b := EvalComplex(e.A, z), EvalComplex(e.B, z)
+		if imag(a) == 0 && imag(b) == 0 {
+			return complex(mod(real(a), real(b)), 0)
+		}
+		q := a / b
+		return a - b*complex(math.Floor(real(q)), math.Floor(imag(q)))
+	case Erf:
+		return erfC(EvalComplex(e.X, z))
+	case Erfc:
+		return erfcC(EvalComplex(e.X, z))
+	case Gamma:
+		return gammaC(EvalComplex(e.X, z))
+	case LogGamma:
+		return lgammaC(EvalComplex(e.X, z))
+	case Digamma:
+		return polygammaC(0, EvalComplex(e.X, z))
+	case Polygamma:
+		return polygammaC(e.N, EvalComplex(e.X, z))
+	case Beta:
+		return betaC(EvalComplex(e.A, z), EvalComplex(e.B, z))
+	case BesselJ:
+		j, _ := besselC(e.N, EvalComplex(e.X, z), false)
+		return j
+	case BesselY:
+		_, y := besselC(e.N, EvalComplex(e.X, z), true)
+		return y
+	case Call:
+		return EvalComplex(e.Inline(), z)
+	case Greater:
+		return branchC(real(EvalComplex(e.A, z)) > real(EvalComplex(e.B, z)), e.If, e.Else, z)
+	case Less:
+		return branchC(real(EvalComplex(e.A, z)) < real(EvalComplex(e.B, z)), e.If, e.Else, z)
+	case GreaterEqual:
+		return branchC(real(EvalComplex(e.A, z)) >= real(EvalComplex(e.B, z)), e.If, e.Else, z)
+	case LessEqual:
+		return branchC(real(EvalComplex(e.A, z)) <= real(EvalComplex(e.B, z)), e.If, e.Else, z)
+	case Equal:
+		return branchC(EvalComplex(e.A, z) == EvalComplex(e.B, z), e.If, e.Else, z)
+	case NotEqual:
+		return branchC(EvalComplex(e.A, z) != EvalComplex(e.B, z), e.If, e.Else, z)
+	case Range:
+		v := real(EvalComplex(e.X, z))
+		return branchC(v >= real(EvalComplex(e.A, z)) && v <= real(EvalComplex(e.B, z)), e.If, e.Else, z)
+	}
+
+	if imag(z) == 0 {
+		return complex(term.E(real(z)), 0)
+	}
+
+	return cmplx.NaN()
+}
+
+// powC raises a to the power of b, keeping integer powers of real numbers exactly real
+func powC(a, b complex128) complex128 {
+	if imag(a) == 0 && imag(b) == 0 && (real(a) >= 0 || real(b) == math.Trunc(real(b))) {
+		return complex(math.Pow(real(a), real(b)), 0)
+	}
+
+	return cmplx.Pow(a, b)
+}
+
+// branchC evaluates whichever branch of a conditional is taken
+func branchC(cond bool, ifTrue, ifFalse Term, z complex128) complex128 {
+	if cond {
+		return EvalComplex(ifTrue, z)
+	}
+
+	return EvalComplex(ifFalse, z)
+}
+
+// erfC returns the error function of z, from its taylor series near the imaginary axis and the continued fraction of erfc elsewhere
+func erfC(z complex128) complex128 {
+	if imag(z) == 0 {
+		return complex(math.Erf(real(z)), 0)
+	}
+
+	if real(z) < 0 {
+		return -erfC(-z)
+	}
+
+	if real(z) < 1 || cmplx.Abs(z) < 3 {
+		return erfSeries(z)
+	}
+
+	return 1 - erfcFraction(z)
+}
+
+// erfcC returns 1 - erf(z), without the cancellation of subtracting where erf is close to 1
+func erfcC(z complex128) complex128 {
+	if imag(z) == 0 {
+		return complex(math.Erfc(real(z)), 0)
+	}
+
+	if real(z) < 0 {
+		return 2 - erfcC(-z)
+	}
+
+	if real(z) < 1 || cmplx.Abs(z) < 3 {
+		return 1 - erfSeries(z)
+	}
+
+	return erfcFraction(z)
+}
+
+// erfSeries sums erf(z) = 2 / sqrt(pi) sum (-1)^n z^(2n+1) / (n! (2n+1)), which only cancels badly when the real part of z is large
+func erfSeries(z complex128) complex128 {
+	z2 := -z * z
+	term, sum := z, z
+
+	for n := 1; n < 10000; n++ {
+		term *= z2 / complex(float64(n), 0)
+		add := term / complex(float64(2*n+1), 0)
+		sum += add
+
+		if cmplx.Abs(add) <= 1e-17*cmplx.Abs(sum) {
+			break
+		}
+	}
+
+	return sum * complex(2/math.Sqrt(math.Pi), 0)
+}
+
+// erfcFraction evaluates the continued fraction erfc(z) = exp(-z^2) / sqrt(pi) / (z + (1/2) / (z + 1 / (z + (3/2) / (z + ...)))) with lentz's method.
+// It converges for positive real parts, and quickly once |z| is large.
+func erfcFraction(z complex128) complex128 {
+	const tiny = 1e-300
+
+	f := z
+	c, d := f, complex128(0)
+
+	for k := 1; k < 10000; k++ {
+		a := complex(float64(k)/2, 0)
+
+		d = z + a*d
+		if d == 0 {
+			d = tiny
+		}
+		d = 1 / d
+
+		c = z + a/c
+		if c == 0 {
+			c = tiny
+		}
+
+		delta := c * d
+		f *= delta
+
+		if cmplx.Abs(delta-1) < 1e-16 {
+			break
+		}
+	}
+
+	return cmplx.Exp(-z*z) / (complex(math.Sqrt(math.Pi), 0) * f)
+}
+
+// lanczos holds the coefficients of the lanczos approximation to gamma with g = 7
+var lanczos = [...]float64{
+	0.99999999999980993,
+	676.5203681218851,
+	-1259.1392167224028,
+	771.32342877765313,
+	-176.61502916214059,
+	12.507343278686905,
+	-0.13857109526572012,
+	9.9843695780195716e-6,
+	1.5056327351493116e-7,
+}
+
+// isPoleC returns true if z is a pole of gamma, and so of digamma and polygamma
+func isPoleC(z complex128) bool {
+	return imag(z) == 0 && isPole(real(z))
+}
+
+// gammaC returns gamma(z), reflecting arguments with negative real parts into the right half plane
+func gammaC(z complex128) complex128 {
+	if imag(z) == 0 {
+		return complex(gamma(real(z)), 0)
+	}
+
+	if real(z) < 0.5 {
+		return complex(math.Pi, 0) / (cmplx.Sin(complex(math.Pi, 0)*z) * gammaC(1-z))
+	}
+
+	return cmplx.Exp(lgammaC(z))
+}
+
+// lgammaC returns the principal branch of log gamma(z), which is analytic everywhere except the negative real axis.
+// Arguments with small real parts are shifted right with log gamma(z) = log gamma(z + 1) - log z, which keeps the branch.
+func lgammaC(z complex128) complex128 {
+	if imag(z) == 0 && real(z) > 0 {
+		return complex(lgamma(real(z)), 0)
+	}
+
+	if isPoleC(z) || real(z) < -1e6 || cmplx.IsNaN(z) {
+		return cmplx.NaN()
+	}
+
+	var shift complex128
+	for real(z) < 0.5 {
+		shift += cmplx.Log(z)
+		z++
+	}
+
+	z--
+	sum := complex(lanczos[0], 0)
+	for i := 1; i < len(lanczos); i++ {
+		sum += complex(lanczos[i], 0) / (z + complex(float64(i), 0))
+	}
+
+	t := z + 7.5
+	return complex(0.5*math.Log(2*math.Pi), 0) + (z+0.5)*cmplx.Log(t) - t + cmplx.Log(sum) - shift
+}
+
+// polygammaC returns the nth derivative of digamma at z, shifting it right like polygamma until the asymptotic series is accurate
+func polygammaC(n int, z complex128) complex128 {
+	if imag(z) == 0 {
+		return complex(polygamma(n, real(z)), 0)
+	}
+
+	if n < 0 || cmplx.IsNaN(z) || real(z) < -1e6 {
+		return cmplx.NaN()
+	}
+
+	// Use the reflection formula for digamma far left of zero, where shifting would take too long
+	if n == 0 && real(z) < -100 {
+		return polygammaC(0, 1-z) - complex(math.Pi, 0)/cmplx.Tan(complex(math.Pi, 0)*z)
+	}
+
+	fact := factorial(n)
+
+	// sign is (-1)^(n+1)
+	sign := -1.0
+	if n%2 == 1 {
+		sign = 1
+	}
+
+	var shift complex128
+	limit := 20 + float64(n)
+	for real(z) < limit {
+		if n == 0 {
+			shift -= 1 / z
+		} else {
+			shift += complex(sign*fact, 0) / cmplx.Pow(z, complex(float64(n+1), 0))
+		}
+		z++
+	}
+
+	// bernoulli holds B_2k / (2k)!, for the asymptotic series
+	bernoulli := []float64{
+		1.0 / 6 / 2,
+		-1.0 / 30 / 24,
+		1.0 / 42 / 720,
+		-1.0 / 30 / 40320,
+		5.0 / 66 / 3628800,
+		-691.0 / 2730 / 479001600,
+		7.0 / 6 / 87178291200,
+	}
+
+	pow := func(k int) complex128 {
+		return cmplx.Pow(z, complex(float64(k), 0))
+	}
+
+	if n == 0 {
+		out := cmplx.Log(z) - 1/(2*z)
+		for k, b := range bernoulli {
+			out -= complex(b*factorial(2*k+1), 0) / pow(2*k+2)
+		}
+		return out + shift
+	}
+
+	out := complex(factorial(n-1), 0)/pow(n) + complex(fact/2, 0)/pow(n+1)
+	for k, b := range bernoulli {
+		out += complex(b*factorial(2*k+2+n-1), 0) / pow(2*k+2+n)
+	}
+
+	return complex(sign, 0)*out + shift
+}
+
+// betaC returns gamma(a) gamma(b) / gamma(a + b), through log gamma so that it doesn't overflow
+func betaC(a, b complex128) complex128 {
+	if imag(a) == 0 && imag(b) == 0 {
+		return complex(beta(real(a), real(b)), 0)
+	}
+
+	if isPoleC(a) || isPoleC(b) {
+		return cmplx.NaN()
+	} else if isPoleC(a + b) {
+		return 0
+	}
+
+	return cmplx.Exp(lgammaC(a) + lgammaC(b) - lgammaC(a+b))
+}
+
+// eulerGamma is the euler-mascheroni constant, digamma(1) = -eulerGamma
+const eulerGamma = 0.57721566490153286
+
+// besselC returns the bessel functions J and, if needed, Y of integer order n at z.
+// They are summed from their series near 0, and from the asymptotic expansions of the hankel functions further out, where the series cancel.
+func besselC(n int, z complex128, needY bool) (complex128, complex128) {
+	if imag(z) == 0 && (!needY || real(z) > 0) {
+		return complex(math.Jn(n, real(z)), 0), complex(math.Yn(n, real(z)), 0)
+	}
+
+	if cmplx.IsNaN(z) {
+		return cmplx.NaN(), cmplx.NaN()
+	}
+
+	// J_-n = (-1)^n J_n, and the same for Y
+	sign := complex128(1)
+	if n < 0 {
+		n = -n
+		if n%2 == 1 {
+			sign = -1
+		}
+	}
+
+	var j, y complex128
+	if cmplx.Abs(z) <= 12 {
+		j, y = besselSeries(n, z, needY)
+	} else {
+		j, y = besselAsymptotic(n, z)
+	}
+
+	return sign * j, sign * y
+}
+
+// besselSeries sums the power series of J_n and Y_n for n >= 0
+func besselSeries(n int, z complex128, needY bool) (complex128, complex128) {
+	half := z / 2
+	q := -half * half
+
+	// term is (-z^2 / 4)^k / (k! (n + k)!), and psi is digamma(k + 1) + digamma(n + k + 1)
+	term := complex(1/factorial(n), 0)
+	psi := -2*eulerGamma + harmonic(n)
+
+	var j, ys complex128
+	for k := 0; k < 1000; k++ {
+		j += term
+		ys += complex(psi, 0) * term
+
+		if cmplx.Abs(term) <= 1e-17*cmplx.Abs(j) && cmplx.Abs(term) <= 1e-17*cmplx.Abs(ys) {
+			break
+		}
+
+		term *= q / complex(float64((k+1)*(n+k+1)), 0)
+		psi += 1/float64(k+1) + 1/float64(n+k+1)
+	}
+
+	halfN := cmplx.Pow(half, complex(float64(n), 0))
+	j *= halfN
+
+	if !needY {
+		return j, 0
+	}
+
+	// The finite sum of (n - k - 1)! / k! (z / 2)^(2k - n)
+	var finite complex128
+	for k := 0; k < n; k++ {
+		finite += complex(factorial(n-k-1)/factorial(k), 0) * cmplx.Pow(half, complex(float64(2*k-n), 0))
+	}
+
+	y := (2*j*cmplx.Log(half) - finite - halfN*ys) / math.Pi
+
+	return j, y
+}
+
+// besselAsymptotic evaluates J_n and Y_n for large |z| from the hankel expansions
+// H_n(z) ~ sqrt(2 / (pi z)) exp(+-i w) sum (+-i)^k a_k / z^k, where w = z - n pi / 2 - pi / 4
+func besselAsymptotic(n int, z complex128) (complex128, complex128) {
+	mu := 4 * float64(n*n)
+
+	var p, q complex128
+	a := complex128(1)
+	last := math.Inf(1)
+
+	for k := 0; k < 100; k++ {
+		if size := cmplx.Abs(a); size > last || size < 1e-17*cmplx.Abs(p) {
+			break
+		} else {
+			last = size
+		}
+
+		// Even terms go to the real part of the sum and odd ones to the imaginary part, with alternating signs
+		switch k % 4 {
+		case 0:
+			p += a
+		case 1:
+			q += a
+		case 2:
+			p -= a
+		case 3:
+			q -= a
+		}
+
+		odd := float64(2*k + 1)
+		a *= complex((mu-odd*odd)/(8*float64(k+1)), 0) / z
+	}
+
+	w := z - complex(float64(n)*math.Pi/2+math.Pi/4, 0)
+	scale := cmplx.Sqrt(2 / (math.Pi * z))
+	cos, sin := cmplx.Cos(w), cmplx.Sin(w)
+
+	return scale * (p*cos - q*sin), scale * (p*sin + q*cos)
+}
+
+// harmonic returns the nth harmonic number
+func harmonic(n int) float64 {
+	var out float64
+	for k := 1; k <= n; k++ {
+		out += 1 / float64(k)
+	}
+
+	return out
+}
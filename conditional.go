@@ -1,5 +1,64 @@
 package alg
 
+func init() {
+	register(TidGreater, Registration{
+		Name:  ">",
+		Arity: 4,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Greater{A: args[0], B: args[1], If: args[2], Else: args[3]}, nil
+		},
+		Type: Greater{},
+	})
+	register(TidLess, Registration{
+		Name:  "<",
+		Arity: 4,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Less{A: args[0], B: args[1], If: args[2], Else: args[3]}, nil
+		},
+		Type: Less{},
+	})
+	register(TidGreaterEqual, Registration{
+		Name:  ">=",
+		Arity: 4,
+		New: func(_ float64, args []Term) (Term, error) {
+			return GreaterEqual{A: args[0], B: args[1], If: args[2], Else: args[3]}, nil
+		},
+		Type: GreaterEqual{},
+	})
+	register(TidLessEqual, Registration{
+		Name:  "<=",
+		Arity: 4,
+		New: func(_ float64, args []Term) (Term, error) {
+			return LessEqual{A: args[0], B: args[1], If: args[2], Else: args[3]}, nil
+		},
+		Type: LessEqual{},
+	})
+	register(TidEqual, Registration{
+		Name:  "==",
+		Arity: 4,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Equal{A: args[0], B: args[1], If: args[2], Else: args[3]}, nil
+		},
+		Type: Equal{},
+	})
+	register(TidNotEqual, Registration{
+		Name:  "!=",
+		Arity: 4,
+		New: func(_ float64, args []Term) (Term, error) {
+			return NotEqual{A: args[0], B: args[1], If: args[2], Else: args[3]}, nil
+		},
+		Type: NotEqual{},
+	})
+	register(TidRange, Registration{
+		Name:  "<=>",
+		Arity: 5,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Range{X: args[0], A: args[1], B: args[2], If: args[3], Else: args[4]}, nil
+		},
+		Type: Range{},
+	})
+}
+
 type Greater struct {
 	A, B, If, Else Term
 }
@@ -25,22 +84,24 @@ func (e Greater) Dx() Term {
 }
 
 func (e Greater) T() Term {
+	e = simplified(e)
+
 	aOk, av := e.A.Is()
 	bOk, bv := e.B.Is()
 
 	if aOk && bOk {
 		if av > bv {
-			return e.If.T()
+			return e.If
 		} else {
-			return e.Else.T()
+			return e.Else
 		}
 	}
 
 	return Greater{
-		A:    e.A.T(),
-		B:    e.B.T(),
-		If:   e.If.T(),
-		Else: e.Else.T(),
+		A:    e.A,
+		B:    e.B,
+		If:   e.If,
+		Else: e.Else,
 	}
 }
 
@@ -59,6 +120,15 @@ func (e Greater) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Greater) Children() []Term {
+	return []Term{e.A, e.B, e.If, e.Else}
+}
+
+func (e Greater) WithChildren(children []Term) Term {
+	e.A, e.B, e.If, e.Else = children[0], children[1], children[2], children[3]
+	return e
+}
+
 func (e Greater) Tokenise() Tokens {
 	t := Tokens{{id: TidGreater}}
 	t = append(t, e.A.Tokenise()...)
@@ -94,22 +164,24 @@ func (e Less) Dx() Term {
 }
 
 func (e Less) T() Term {
+	e = simplified(e)
+
 	aOk, av := e.A.Is()
 	bOk, bv := e.B.Is()
 
 	if aOk && bOk {
 		if av < bv {
-			return e.If.T()
+			return e.If
 		} else {
-			return e.Else.T()
+			return e.Else
 		}
 	}
 
 	return Less{
-		A:    e.A.T(),
-		B:    e.B.T(),
-		If:   e.If.T(),
-		Else: e.Else.T(),
+		A:    e.A,
+		B:    e.B,
+		If:   e.If,
+		Else: e.Else,
 	}
 }
 
@@ -128,6 +200,15 @@ func (e Less) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Less) Children() []Term {
+	return []Term{e.A, e.B, e.If, e.Else}
+}
+
+func (e Less) WithChildren(children []Term) Term {
+	e.A, e.B, e.If, e.Else = children[0], children[1], children[2], children[3]
+	return e
+}
+
 func (e Less) Tokenise() Tokens {
 	t := Tokens{{id: TidLess}}
 	t = append(t, e.A.Tokenise()...)
@@ -163,22 +244,24 @@ func (e GreaterEqual) Dx() Term {
 }
 
 func (e GreaterEqual) T() Term {
+	e = simplified(e)
+
 	aOk, av := e.A.Is()
 	bOk, bv := e.B.Is()
 
 	if aOk && bOk {
 		if av >= bv {
-			return e.If.T()
+			return e.If
 		} else {
-			return e.Else.T()
+			return e.Else
 		}
 	}
 
 	return GreaterEqual{
-		A:    e.A.T(),
-		B:    e.B.T(),
-		If:   e.If.T(),
-		Else: e.Else.T(),
+		A:    e.A,
+		B:    e.B,
+		If:   e.If,
+		Else: e.Else,
 	}
 }
 
@@ -197,6 +280,15 @@ func (e GreaterEqual) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e GreaterEqual) Children() []Term {
+	return []Term{e.A, e.B, e.If, e.Else}
+}
+
+func (e GreaterEqual) WithChildren(children []Term) Term {
+	e.A, e.B, e.If, e.Else = children[0], children[1], children[2], children[3]
+	return e
+}
+
 func (e GreaterEqual) Tokenise() Tokens {
 	t := Tokens{{id: TidGreaterEqual}}
 	t = append(t, e.A.Tokenise()...)
@@ -232,22 +324,24 @@ func (e LessEqual) Dx() Term {
 }
 
 func (e LessEqual) T() Term {
+	e = simplified(e)
+
 	aOk, av := e.A.Is()
 	bOk, bv := e.B.Is()
 
 	if aOk && bOk {
 		if av <= bv {
-			return e.If.T()
+			return e.If
 		} else {
-			return e.Else.T()
+			return e.Else
 		}
 	}
 
 	return LessEqual{
-		A:    e.A.T(),
-		B:    e.B.T(),
-		If:   e.If.T(),
-		Else: e.Else.T(),
+		A:    e.A,
+		B:    e.B,
+		If:   e.If,
+		Else: e.Else,
 	}
 }
 
@@ -266,6 +360,15 @@ func (e LessEqual) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e LessEqual) Children() []Term {
+	return []Term{e.A, e.B, e.If, e.Else}
+}
+
+func (e LessEqual) WithChildren(children []Term) Term {
+	e.A, e.B, e.If, e.Else = children[0], children[1], children[2], children[3]
+	return e
+}
+
 func (e LessEqual) Tokenise() Tokens {
 	t := Tokens{{id: TidLessEqual}}
 	t = append(t, e.A.Tokenise()...)
@@ -298,22 +401,24 @@ func (e Equal) Dx() Term {
 }
 
 func (e Equal) T() Term {
+	e = simplified(e)
+
 	aOk, av := e.A.Is()
 	bOk, bv := e.B.Is()
 
 	if aOk && bOk {
 		if av == bv {
-			return e.If.T()
+			return e.If
 		} else {
-			return e.Else.T()
+			return e.Else
 		}
 	}
 
 	return Equal{
-		A:    e.A.T(),
-		B:    e.B.T(),
-		If:   e.If.T(),
-		Else: e.Else.T(),
+		A:    e.A,
+		B:    e.B,
+		If:   e.If,
+		Else: e.Else,
 	}
 }
 
@@ -332,6 +437,15 @@ func (e Equal) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Equal) Children() []Term {
+	return []Term{e.A, e.B, e.If, e.Else}
+}
+
+func (e Equal) WithChildren(children []Term) Term {
+	e.A, e.B, e.If, e.Else = children[0], children[1], children[2], children[3]
+	return e
+}
+
 func (e Equal) Tokenise() Tokens {
 	t := Tokens{{id: TidEqual}}
 	t = append(t, e.A.Tokenise()...)
@@ -363,22 +477,24 @@ func (e NotEqual) Dx() Term {
 }
 
 func (e NotEqual) T() Term {
+	e = simplified(e)
+
 	aOk, av := e.A.Is()
 	bOk, bv := e.B.Is()
 
 	if aOk && bOk {
 		if av != bv {
-			return e.If.T()
+			return e.If
 		} else {
-			return e.Else.T()
+			return e.Else
 		}
 	}
 
 	return NotEqual{
-		A:    e.A.T(),
-		B:    e.B.T(),
-		If:   e.If.T(),
-		Else: e.Else.T(),
+		A:    e.A,
+		B:    e.B,
+		If:   e.If,
+		Else: e.Else,
 	}
 }
 
@@ -397,6 +513,15 @@ func (e NotEqual) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e NotEqual) Children() []Term {
+	return []Term{e.A, e.B, e.If, e.Else}
+}
+
+func (e NotEqual) WithChildren(children []Term) Term {
+	e.A, e.B, e.If, e.Else = children[0], children[1], children[2], children[3]
+	return e
+}
+
 func (e NotEqual) Tokenise() Tokens {
 	t := Tokens{{id: TidNotEqual}}
 	t = append(t, e.A.Tokenise()...)
@@ -431,24 +556,26 @@ func (e Range) Dx() Term {
 }
 
 func (e Range) T() Term {
+	e = simplified(e)
+
 	xOk, xv := e.X.Is()
 	aOk, av := e.A.Is()
 	bOk, bv := e.B.Is()
 
 	if xOk && aOk && bOk {
 		if xv >= av && xv <= bv {
-			return e.If.T()
+			return e.If
 		} else {
-			return e.Else.T()
+			return e.Else
 		}
 	}
 
 	return Range{
-		X:    e.X.T(),
-		A:    e.A.T(),
-		B:    e.B.T(),
-		If:   e.If.T(),
-		Else: e.Else.T(),
+		X:    e.X,
+		A:    e.A,
+		B:    e.B,
+		If:   e.If,
+		Else: e.Else,
 	}
 }
 
@@ -468,6 +595,15 @@ func (e Range) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Range) Children() []Term {
+	return []Term{e.X, e.A, e.B, e.If, e.Else}
+}
+
+func (e Range) WithChildren(children []Term) Term {
+	e.X, e.A, e.B, e.If, e.Else = children[0], children[1], children[2], children[3], children[4]
+	return e
+}
+
 func (e Range) Tokenise() Tokens {
 	t := Tokens{{id: TidRange}}
 	t = append(t, e.X.Tokenise()...)
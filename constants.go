@@ -0,0 +1,149 @@
+package alg
+
+import (
+	"math"
+)
+
+/*
+Constants defines named mathematical constants:
+  Pi     => The ratio of a circle's circumference to its diameter
+  EulerE => The base of the natural logarithm
+  Phi    => The golden ratio
+Unlike S, they are not constant as far as Is is concerned, so that T doesn't fold them into an inexact float.
+Simplification rules that know about them, like sin(k pi) = 0 and ln(e) = 1, use their exact values instead.
+*/
+
+func init() {
+	register(TidPi, Registration{
+		Name:  "pi",
+		Arity: 0,
+		New: func(_ float64, _ []Term) (Term, error) {
+			return Pi{}, nil
+		},
+		Type: Pi{},
+	})
+	register(TidEulerE, Registration{
+		Name:  "euler",
+		Arity: 0,
+		New: func(_ float64, _ []Term) (Term, error) {
+			return EulerE{}, nil
+		},
+		Type: EulerE{},
+	})
+	register(TidPhi, Registration{
+		Name:  "phi",
+		Arity: 0,
+		New: func(_ float64, _ []Term) (Term, error) {
+			return Phi{}, nil
+		},
+		Type: Phi{},
+	})
+}
+
+type Pi struct{}
+
+func (e Pi) E(_ float64) float64 {
+	return math.Pi
+}
+
+func (e Pi) Dx() Term {
+	return S(0)
+}
+
+func (e Pi) T() Term {
+	return e
+}
+
+func (e Pi) Is() (bool, float64) {
+	return false, 0
+}
+
+func (e Pi) Tokenise() Tokens {
+	return Tokens{{id: TidPi}}
+}
+
+type EulerE struct{}
+
+func (e EulerE) E(_ float64) float64 {
+	return math.E
+}
+
+func (e EulerE) Dx() Term {
+	return S(0)
+}
+
+func (e EulerE) T() Term {
+	return e
+}
+
+func (e EulerE) Is() (bool, float64) {
+	return false, 0
+}
+
+func (e EulerE) Tokenise() Tokens {
+	return Tokens{{id: TidEulerE}}
+}
+
+type Phi struct{}
+
+func (e Phi) E(_ float64) float64 {
+	return math.Phi
+}
+
+func (e Phi) Dx() Term {
+	return S(0)
+}
+
+func (e Phi) T() Term {
+	return e
+}
+
+func (e Phi) Is() (bool, float64) {
+	return false, 0
+}
+
+func (e Phi) Tokenise() Tokens {
+	return Tokens{{id: TidPhi}}
+}
+
+// piMultiple returns k if a term is exactly k pi for a constant k, such as a product of Pi and scalars
+func piMultiple(term Term) (float64, bool) {
+	switch e := term.(type) {
+	case Pi:
+		return 1, true
+	case Prod:
+		k, found := 1.0, false
+		for _, sub := range e {
+			if ok, v := sub.Is(); ok {
+				k *= v
+			} else if v, ok := piMultiple(sub); ok && !found {
+				found = true
+				k *= v
+			} else {
+				return 0, false
+			}
+		}
+		return k, found
+	case Mul:
+		return piMultiple(Prod{e.A, e.B})
+	case Div:
+		k, ok := piMultiple(e.N)
+		if dok, d := e.D.Is(); ok && dok && d != 0 {
+			return k / d, true
+		}
+	case Add:
+		a, aok := piMultiple(e.A)
+		b, bok := piMultiple(e.B)
+		if aok && bok {
+			return a + b, true
+		}
+	case Sub:
+		a, aok := piMultiple(e.A)
+		b, bok := piMultiple(e.B)
+		if aok && bok {
+			return a - b, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,196 @@
+package alg
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DAG is a term with every structurally equal subtree stored once, so that each is only evaluated once.
+// Nodes are in topological order, with children before their parents, and the root last.
+type DAG struct {
+	Nodes []Node
+
+	// scratch holds compiled copies of the nodes for E, so that it doesn't allocate and can be called concurrently
+	once    sync.Once
+	scratch sync.Pool
+}
+
+// Node is one unique subtree of a DAG
+type Node struct {
+	// Term is the first subtree found with this structure
+	Term Term
+
+	// Children are the indices of the node's children in the DAG, in the same order as Children(Term)
+	Children []int
+
+	// Uses is the number of times the subtree appears in the original tree
+	Uses int
+}
+
+// NewDAG hash-conses a term into a DAG.
+// Terms that don't implement Parent are kept whole, and only shared with terms that tokenise the same way.
+func NewDAG(term Term) *DAG {
+	d := &DAG{}
+	index := make(map[string]int)
+
+	var add func(t Term) int
+	add = func(t Term) int {
+		sub := Children(t)
+
+		children := make([]int, len(sub))
+		for i, child := range sub {
+			children[i] = add(child)
+		}
+
+		// A node is identified by its own type and scalars, and the nodes of its children
+		k := shape(t) + fmt.Sprint(children)
+
+		if i, ok := index[k]; ok {
+			return i
+		}
+
+		index[k] = len(d.Nodes)
+		d.Nodes = append(d.Nodes, Node{Term: t, Children: children})
+		return len(d.Nodes) - 1
+	}
+
+	add(term)
+
+	// Each use of a node uses its children once more, and parents come after their children
+	d.Nodes[len(d.Nodes)-1].Uses = 1
+	for i := len(d.Nodes) - 1; i >= 0; i-- {
+		for _, c := range d.Nodes[i].Children {
+			d.Nodes[c].Uses += d.Nodes[i].Uses
+		}
+	}
+
+	return d
+}
+
+// shape identifies a term by its own type and scalars, and its number of children, ignoring what the children are
+func shape(term Term) string {
+	k := fmt.Sprintf("%T", term)
+
+	p, ok := term.(Parent)
+	if !ok {
+		return k + key(term)
+	}
+
+	sub := make([]Term, len(p.Children()))
+	for i := range sub {
+		sub[i] = X{}
+	}
+
+	return k + key(p.WithChildren(sub))
+}
+
+// E evaluates the DAG at x, computing each unique node once.
+// Conditionals evaluate both of their branches, since each node is computed before anything that uses it.
+// E is safe to call concurrently, but Nodes mustn't change once it has been called.
+func (d *DAG) E(x float64) float64 {
+	d.once.Do(func() {
+		d.scratch.New = func() any {
+			return d.compile()
+		}
+	})
+
+	s := d.scratch.Get().(*scratch)
+
+	for i, t := range s.terms {
+		s.vals[i] = t.E(x)
+	}
+	v := s.vals[len(s.vals)-1]
+
+	d.scratch.Put(s)
+	return v
+}
+
+// scratch is a copy of the nodes of a DAG with their children replaced by slots, which read the values of the nodes from vals
+type scratch struct {
+	vals  []float64
+	terms []Term
+}
+
+// compile makes scratch for evaluating the DAG
+func (d *DAG) compile() *scratch {
+	s := &scratch{
+		vals:  make([]float64, len(d.Nodes)),
+		terms: make([]Term, len(d.Nodes)),
+	}
+
+	for i, n := range d.Nodes {
+		if len(n.Children) == 0 {
+			s.terms[i] = n.Term
+			continue
+		}
+
+		args := make([]Term, len(n.Children))
+		for j, c := range n.Children {
+			args[j] = slot{&s.vals[c]}
+		}
+
+		s.terms[i] = n.Term.(Parent).WithChildren(args)
+	}
+
+	return s
+}
+
+// slot is a constant whose value is read when evaluated, for the value of a node that has already been computed
+type slot struct {
+	v *float64
+}
+
+func (e slot) E(_ float64) float64 {
+	return *e.v
+}
+
+func (e slot) Dx() Term {
+	return S(0)
+}
+
+func (e slot) T() Term {
+	return S(*e.v)
+}
+
+func (e slot) Is() (bool, float64) {
+	return true, *e.v
+}
+
+func (e slot) Tokenise() Tokens {
+	return S(*e.v).Tokenise()
+}
+
+// Shared is a subtree that appears more than once in a term
+type Shared struct {
+	Term Term
+
+	// Uses is the number of times the subtree appears, and Size is the number of terms in it
+	Uses, Size int
+}
+
+// CSE finds the common subexpressions of a term, the subtrees with children that appear more than once.
+// They are sorted by the number of terms that sharing them saves, most first.
+func CSE(term Term) []Shared {
+	d := NewDAG(term)
+
+	size := make([]int, len(d.Nodes))
+	out := make([]Shared, 0)
+
+	for i, n := range d.Nodes {
+		size[i] = 1
+		for _, c := range n.Children {
+			size[i] += size[c]
+		}
+
+		if n.Uses > 1 && len(n.Children) > 0 {
+			out = append(out, Shared{Term: n.Term, Uses: n.Uses, Size: size[i]})
+		}
+	}
+
+	sort.SliceStable(out, func(a, b int) bool {
+		return (out[a].Uses-1)*out[a].Size > (out[b].Uses-1)*out[b].Size
+	})
+
+	return out
+}
@@ -0,0 +1,233 @@
+package alg
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// DerivativeCache memoises derivatives by the structure of the term they were taken of.
+// Sums and products are differentiated through the cache, so shared subterms are only differentiated once.
+type DerivativeCache struct {
+	dx map[string]Term
+}
+
+// NewDerivativeCache returns an empty derivative cache
+func NewDerivativeCache() *DerivativeCache {
+	return &DerivativeCache{
+		dx: make(map[string]Term),
+	}
+}
+
+// DxN returns the nth derivative of a term, using a fresh cache.
+func DxN(term Term, n int) Term {
+	return NewDerivativeCache().DxN(term, n)
+}
+
+// DxN returns the nth derivative of a term, simplifying after each step.
+func (c *DerivativeCache) DxN(term Term, n int) Term {
+	d := term.T()
+
+	for i := 0; i < n; i++ {
+		d = c.Dx(d)
+	}
+
+	return d
+}
+
+// Dx returns the simplified derivative of a term, reusing any previously computed result.
+func (c *DerivativeCache) Dx(term Term) Term {
+	k := key(term)
+
+	if d, ok := c.dx[k]; ok {
+		return d
+	}
+
+	var d Term
+
+	switch e := term.(type) {
+	case Sum:
+		sum := make(Sum, len(e))
+		for i, sub := range e {
+			sum[i] = c.Dx(sub)
+		}
+		d = gather(sum)
+	case Add:
+		d = gather(Sum{c.Dx(e.A), c.Dx(e.B)})
+	case Sub:
+		d = gather(Sum{c.Dx(e.A), Prod{S(-1), c.Dx(e.B)}})
+	case Prod:
+		sum := make(Sum, len(e))
+		for i := range e {
+			prod := make(Prod, 0, len(e))
+			prod = append(prod, c.Dx(e[i]))
+			prod = append(prod, e[:i]...)
+			prod = append(prod, e[i+1:]...)
+			sum[i] = collect(prod)
+		}
+		d = gather(sum)
+	case Mul:
+		d = gather(Sum{
+			collect(Prod{c.Dx(e.A), e.B}),
+			collect(Prod{c.Dx(e.B), e.A}),
+		})
+	case Div:
+		// Keep the denominator as a power, so that repeated derivatives raise it by one rather than squaring it.
+		g, p := e.D, 1.0
+		if tp, ok := e.D.(TP); ok {
+			g, p = tp.X, tp.P
+		} else if m, ok := e.D.(Mul); ok && key(m.A) == key(m.B) {
+			g, p = m.A, 2
+		}
+
+		d = Div{
+			N: gather(Sum{
+				collect(Prod{c.Dx(e.N), g}),
+				collect(Prod{S(-p), e.N, c.Dx(g)}),
+			}),
+			D: TP{g, p + 1},
+		}.T()
+	default:
+		d = term.Dx().T()
+	}
+
+	c.dx[k] = d
+
+	return d
+}
+
+// Len returns the number of derivatives held in the cache
+func (c *DerivativeCache) Len() int {
+	return len(c.dx)
+}
+
+// collect simplifies a product, merging repeated factors into powers.
+func collect(p Prod) Term {
+	var coef float64 = 1
+	bases := make([]Term, 0, len(p))
+	powers := make([]float64, 0, len(p))
+	index := make(map[string]int)
+
+	for _, term := range p.flatten() {
+		if ok, val := term.Is(); ok {
+			coef *= val
+			continue
+		}
+
+		base, power := term.T(), 1.0
+		if tp, ok := base.(TP); ok {
+			base, power = tp.X, tp.P
+		}
+
+		k := key(base)
+		if i, ok := index[k]; ok {
+			powers[i] += power
+			continue
+		}
+
+		index[k] = len(bases)
+		bases = append(bases, base)
+		powers = append(powers, power)
+	}
+
+	out := make(Prod, 0, len(bases)+1)
+	for i, base := range bases {
+		out = append(out, TP{base, powers[i]}.T())
+	}
+
+	// Sort the factors so that equal products have equal keys
+	sort.Slice(out, func(i, j int) bool {
+		return key(out[i]) < key(out[j])
+	})
+
+	out = append(out, S(coef))
+
+	return out.T()
+}
+
+// gather simplifies a sum, adding together the coefficients of terms that only differ by a scalar factor.
+func gather(s Sum) Term {
+	var total float64
+	terms := make([]Term, 0, len(s))
+	coefs := make([]float64, 0, len(s))
+	index := make(map[string]int)
+
+	for _, term := range s.flatten() {
+		if ok, val := term.Is(); ok {
+			total += val
+			continue
+		}
+
+		term, coef := split(term.T())
+
+		k := key(term)
+		if i, ok := index[k]; ok {
+			coefs[i] += coef
+			continue
+		}
+
+		index[k] = len(terms)
+		terms = append(terms, term)
+		coefs = append(coefs, coef)
+	}
+
+	out := make(Sum, 0, len(terms)+1)
+	for i, term := range terms {
+		out = append(out, Prod{term, S(coefs[i])}.T())
+	}
+	out = append(out, S(total))
+
+	return out.T()
+}
+
+// split separates the scalar factor from a term
+func split(term Term) (Term, float64) {
+	var factors Prod
+
+	switch e := term.(type) {
+	case Prod:
+		factors = e.flatten()
+	case Mul:
+		factors = Prod{e}.flatten()
+	case Sx:
+		return X{}, e.S
+	default:
+		return term, 1
+	}
+
+	var coef float64 = 1
+	rest := make(Prod, 0, len(factors))
+	for _, factor := range factors {
+		if ok, val := factor.Is(); ok {
+			coef *= val
+		} else {
+			rest = append(rest, factor)
+		}
+	}
+
+	if len(rest) == 1 {
+		return rest[0], coef
+	}
+
+	return rest, coef
+}
+
+// key converts a term into a string that is equal for structurally equal terms.
+// Unlike Tokens.String() it keeps the full precision of scalars.
+func key(term Term) string {
+	t := term.Tokenise()
+	b := make([]byte, 0, len(t)*9)
+
+	for _, token := range t {
+		b = binary.AppendUvarint(b, uint64(token.id))
+
+		if token.id == TidWild || token.id == TidCall {
+			b = binary.AppendUvarint(b, uint64(len(token.name)))
+			b = append(b, token.name...)
+		} else if registry[token.id].Valued {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(token.val))
+		}
+	}
+
+	return string(b)
+}
@@ -0,0 +1,93 @@
+package alg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edit replaces the subtree at Path, Old, with New.
+// Path holds the index of the child taken at each step from the root, in the same order as Children.
+type Edit struct {
+	Path     []int
+	Old, New Term
+}
+
+func (e Edit) String() string {
+	o, n := e.Old.Tokenise(), e.New.Tokenise()
+	return fmt.Sprintf("at %v: %s=> %s", e.Path, o.String(), strings.TrimSpace(n.String()))
+}
+
+// Edits are the changes between two terms, in the order their paths appear in the tree
+type Edits []Edit
+
+func (d Edits) String() string {
+	if len(d) == 0 {
+		return "no changes"
+	}
+
+	lines := make([]string, len(d))
+	for i, e := range d {
+		lines[i] = e.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Diff returns the edits that turn a into b.
+// Edits are kept as small as possible, so a subtree is only replaced whole if its own type or scalars changed, or its number of children did.
+// Terms that Same reports as equal have no edits.
+func Diff(a, b Term) Edits {
+	d := Edits{}
+
+	var diff func(a, b Term, path []int)
+	diff = func(a, b Term, path []int) {
+		if Same(a, b) {
+			return
+		}
+
+		if shape(a) != shape(b) {
+			d = append(d, Edit{Path: path, Old: a, New: b})
+			return
+		}
+
+		ca, cb := Children(a), Children(b)
+		for i := range ca {
+			sub := make([]int, len(path), len(path)+1)
+			copy(sub, path)
+			diff(ca[i], cb[i], append(sub, i))
+		}
+	}
+
+	diff(a, b, []int{})
+
+	return d
+}
+
+// Apply makes a list of edits to a term, replacing the subtree at each path with the edit's New term
+func (d Edits) Apply(term Term) Term {
+	for _, e := range d {
+		term = replaceAt(term, e.Path, e.New)
+	}
+
+	return term
+}
+
+// replaceAt replaces the subtree of a term at a path
+func replaceAt(term Term, path []int, replacement Term) Term {
+	if len(path) == 0 {
+		return replacement
+	}
+
+	p, ok := term.(Parent)
+	if !ok {
+		return term
+	}
+
+	children := p.Children()
+	if path[0] < 0 || path[0] >= len(children) {
+		return term
+	}
+
+	children[path[0]] = replaceAt(children[path[0]], path[1:], replacement)
+	return p.WithChildren(children)
+}
@@ -0,0 +1,486 @@
+package alg
+
+import (
+	"math"
+	"sort"
+)
+
+// Relation is the way a condition's term must compare to zero
+type Relation int
+
+const (
+	Positive Relation = iota
+	NonNegative
+	NonZero
+	Integer
+)
+
+// Condition is a requirement on the value of a term, such as the argument of a log being positive.
+type Condition struct {
+	Term Term
+	Rel  Relation
+}
+
+// Holds returns true if the condition is met at x
+func (c Condition) Holds(x float64) bool {
+	v := c.Term.E(x)
+
+	switch c.Rel {
+	case Positive:
+		return v > 0
+	case NonNegative:
+		return v >= 0
+	case NonZero:
+		// Look through conditionals to the branch taken, so the special cases below still apply
+		if ifTrue, ifFalse, rebuild, ok := branches(c.Term); ok {
+			if rebuild(S(1), S(0)).E(x) == 1 {
+				return Condition{ifTrue, c.Rel}.Holds(x)
+			}
+			return Condition{ifFalse, c.Rel}.Holds(x)
+		}
+
+		// Zeros of sin and cos can't be represented exactly, so treat them like EvalChecked does
+		switch e := c.Term.(type) {
+		case Sin:
+			return poleAt(v, v, e.X.E(x)) == v
+		case Cos:
+			return poleAt(v, v, e.X.E(x)) == v
+		}
+		return v != 0 && !math.IsNaN(v)
+	case Integer:
+		return v == math.Trunc(v)
+	}
+
+	return false
+}
+
+// Domain derives the conditions that must all hold for a term to be defined.
+// Arguments of Ln must be positive, denominators and the arguments of Tan, Sec, Csc and Cot must keep clear of their poles,
+// non integer powers need a non negative base, and inverse functions need arguments their forward function can return. Conditions inside a branch of a conditional only apply when that branch is taken.
+// TPT with a base and power that both depend on x is only taken to be defined for positive bases.
+func Domain(term Term) []Condition {
+	d := domain{seen: make(map[string]bool)}
+	d.walk(term)
+	return d.conds
+}
+
+// domain collects the conditions of a term, skipping duplicates
+type domain struct {
+	conds []Condition
+	seen  map[string]bool
+}
+
+func (d *domain) add(term Term, rel Relation) {
+	term = term.T()
+
+	// Conditions on constants are either always met or never met, and only the latter matter
+	if !hasX(term) && (Condition{term, rel}).Holds(0) {
+		return
+	}
+
+	k := string(rune(rel)) + key(term)
+	if d.seen[k] {
+		return
+	}
+
+	d.seen[k] = true
+	d.conds = append(d.conds, Condition{Term: term, Rel: rel})
+}
+
+// branch adds the conditions of each branch of a conditional, rebuilt so that they are met when the other branch is taken
+func (d *domain) branch(ifTrue, ifFalse Term, rebuild func(ifTrue, ifFalse Term) Term) {
+	for _, c := range Domain(ifTrue) {
+		d.add(rebuild(c.Term, S(1)), c.Rel)
+	}
+
+	for _, c := range Domain(ifFalse) {
+		d.add(rebuild(S(1), c.Term), c.Rel)
+	}
+}
+
+func (d *domain) walk(term Term) {
+	switch e := term.(type) {
+	case Sum:
+		for _, sub := range e {
+			d.walk(sub)
+		}
+	case Prod:
+		for _, sub := range e {
+			d.walk(sub)
+		}
+	case Add:
+		d.walk(e.A)
+		d.walk(e.B)
+	case Sub:
+		d.walk(e.A)
+		d.walk(e.B)
+	case Mul:
+		d.walk(e.A)
+		d.walk(e.B)
+	case Div:
+		d.walk(e.N)
+		d.walk(e.D)
+		d.add(e.D, NonZero)
+	case Exp:
+		d.walk(e.X)
+	case Ln:
+		d.walk(e.X)
+		d.add(e.X, Positive)
+	case Log:
+		d.walk(e.Base)
+		d.walk(e.X)
+		d.add(e.X, Positive)
+		d.add(e.Base, Positive)
+		d.add(Sub{e.Base, S(1)}, NonZero)
+	case Log2:
+		d.walk(e.X)
+		d.add(e.X, Positive)
+	case Log10:
+		d.walk(e.X)
+		d.add(e.X, Positive)
+	case Sqrt:
+		d.walk(e.X)
+		d.add(e.X, NonNegative)
+	case Cbrt:
+		d.walk(e.X)
+	case TP:
+		d.walk(e.X)
+		d.power(e.X, e.P)
+	case PT:
+		d.walk(e.X)
+		d.base(e.V, e.X)
+	case TPT:
+		d.walk(e.A)
+		d.walk(e.B)
+		if ok, p := e.B.Is(); ok {
+			d.power(e.A, p)
+		} else if ok, v := e.A.Is(); ok {
+			d.base(v, e.B)
+		} else {
+			d.add(e.A, Positive)
+		}
+	case Sin:
+		d.walk(e.X)
+	case Cos:
+		d.walk(e.X)
+	case Tan:
+		d.walk(e.X)
+		d.add(Cos{e.X}, NonZero)
+	case Sec:
+		d.walk(e.X)
+		d.add(Cos{e.X}, NonZero)
+	case Csc:
+		d.walk(e.X)
+		d.add(Sin{e.X}, NonZero)
+	case Cot:
+		d.walk(e.X)
+		d.add(Sin{e.X}, NonZero)
+	case Sinh:
+		d.walk(e.X)
+	case Cosh:
+		d.walk(e.X)
+	case Tanh:
+		d.walk(e.X)
+	case Sech:
+		d.walk(e.X)
+	case Csch:
+		d.walk(e.X)
+		d.add(e.X, NonZero)
+	case Coth:
+		d.walk(e.X)
+		d.add(e.X, NonZero)
+	case Asin:
+		d.walk(e.X)
+		d.add(Sub{S(1), TP{e.X, 2}}, NonNegative)
+	case Acos:
+		d.walk(e.X)
+		d.add(Sub{S(1), TP{e.X, 2}}, NonNegative)
+	case Atan:
+		d.walk(e.X)
+	case Asec:
+		d.walk(e.X)
+		d.add(Sub{TP{e.X, 2}, S(1)}, NonNegative)
+	case Acsc:
+		d.walk(e.X)
+		d.add(Sub{TP{e.X, 2}, S(1)}, NonNegative)
+	case Acot:
+		d.walk(e.X)
+	case Atan2:
+		d.walk(e.Y)
+		d.walk(e.X)
+	case Asinh:
+		d.walk(e.X)
+	case Acosh:
+		d.walk(e.X)
+		d.add(Sub{e.X, S(1)}, NonNegative)
+	case Atanh:
+		d.walk(e.X)
+		d.add(Sub{S(1), TP{e.X, 2}}, Positive)
+	case Asech:
+		d.walk(e.X)
+		d.add(e.X, Positive)
+		d.add(Sub{S(1), e.X}, NonNegative)
+	case Acsch:
+		d.walk(e.X)
+		d.add(e.X, NonZero)
+	case Acoth:
+		d.walk(e.X)
+		d.add(Sub{TP{e.X, 2}, S(1)}, Positive)
+	case Abs:
+		d.walk(e.X)
+	case Sign:
+		d.walk(e.X)
+	case Min:
+		d.walk(e.A)
+		d.walk(e.B)
+	case Max:
+		d.walk(e.A)
+		d.walk(e.B)
+	case Floor:
+		d.walk(e.X)
+	case Ceil:
+		d.walk(e.X)
+	case Mod:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.add(e.B, NonZero)
+	case Erf:
+		d.walk(e.X)
+	case Erfc:
+		d.walk(e.X)
+	case Gamma:
+		d.walk(e.X)
+		d.gamma(e.X)
+	case LogGamma:
+		d.walk(e.X)
+		d.gamma(e.X)
+	case Digamma:
+		d.walk(e.X)
+		d.gamma(e.X)
+	case Polygamma:
+		d.walk(e.X)
+		d.gamma(e.X)
+	case Beta:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.gamma(e.A)
+		d.gamma(e.B)
+	case BesselJ:
+		d.walk(e.X)
+	case BesselY:
+		d.walk(e.X)
+		d.add(e.X, Positive)
+	case Call:
+		d.walk(e.Inline())
+	case Greater:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.branch(e.If, e.Else, func(a, b Term) Term { return Greater{e.A, e.B, a, b} })
+	case Less:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.branch(e.If, e.Else, func(a, b Term) Term { return Less{e.A, e.B, a, b} })
+	case GreaterEqual:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.branch(e.If, e.Else, func(a, b Term) Term { return GreaterEqual{e.A, e.B, a, b} })
+	case LessEqual:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.branch(e.If, e.Else, func(a, b Term) Term { return LessEqual{e.A, e.B, a, b} })
+	case Equal:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.branch(e.If, e.Else, func(a, b Term) Term { return Equal{e.A, e.B, a, b} })
+	case NotEqual:
+		d.walk(e.A)
+		d.walk(e.B)
+		d.branch(e.If, e.Else, func(a, b Term) Term { return NotEqual{e.A, e.B, a, b} })
+	case Range:
+		d.walk(e.X)
+		d.walk(e.A)
+		d.walk(e.B)
+		d.branch(e.If, e.Else, func(a, b Term) Term { return Range{e.X, e.A, e.B, a, b} })
+	}
+}
+
+// gamma adds the conditions for a term to keep clear of the poles of gamma at 0, -1, -2, ...
+func (d *domain) gamma(term Term) {
+	d.add(LessEqual{term, S(0), Sin{Prod{S(math.Pi), term}}, S(1)}, NonZero)
+}
+
+// power adds the conditions for a term to be raised to the power p
+func (d *domain) power(term Term, p float64) {
+	if p != math.Trunc(p) {
+		if p > 0 {
+			d.add(term, NonNegative)
+		} else {
+			d.add(term, Positive)
+		}
+	} else if p < 0 {
+		d.add(term, NonZero)
+	}
+}
+
+// base adds the conditions for v to be raised to the power of a term
+func (d *domain) base(v float64, term Term) {
+	if v == 0 {
+		d.add(term, NonNegative)
+	} else if v < 0 {
+		d.add(term, Integer)
+	}
+}
+
+// DomainIn finds the pieces of a finite interval where a term is defined.
+// The ends of each piece are where a condition of the domain starts or stops holding, so may not be in the domain themselves.
+func DomainIn(term Term, x Interval) []Interval {
+	if !(x.Lo < x.Hi) || math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) {
+		return nil
+	}
+
+	conds := Domain(term)
+	holds := func(v float64) bool {
+		for _, c := range conds {
+			if !c.Holds(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	points := append([]float64{x.Lo}, boundaries(conds, x)...)
+	points = append(points, x.Hi)
+
+	out := make([]Interval, 0)
+	for i := 1; i < len(points); i++ {
+		lo, hi := points[i-1], points[i]
+		if !holds((lo + hi) / 2) {
+			continue
+		}
+
+		// Join pieces that are only split by a point that is itself in the domain
+		if n := len(out); n > 0 && out[n-1].Hi == lo && holds(lo) {
+			out[n-1].Hi = hi
+		} else {
+			out = append(out, Interval{lo, hi})
+		}
+	}
+
+	return out
+}
+
+// Singularities finds the points in a finite interval where a term tends to infinity, from either side.
+// Singularities that can be removed, like sin(x) / x at 0, are not included.
+func Singularities(term Term, x Interval) []float64 {
+	if !(x.Lo <= x.Hi) || math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) {
+		return nil
+	}
+
+	out := make([]float64, 0)
+	for _, p := range boundaries(Domain(term), x) {
+		for _, dir := range []Direction{FromBelow, FromAbove} {
+			if l, err := Limit(term, p, dir); err == nil && math.IsInf(l.Value, 0) {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+
+	return out
+}
+
+// boundaries finds the points in an interval where any of the conditions start or stop holding, in ascending order.
+// Zeros are found exactly where Solve can, and changes are also found numerically so that jumps and poles are not missed.
+func boundaries(conds []Condition, x Interval) []float64 {
+	exact := make([]float64, 0)
+	found := make([]float64, 0)
+
+	for _, c := range conds {
+		// Integer conditions only hold at isolated points, which are not boundaries of any piece
+		if c.Rel == Integer {
+			continue
+		}
+
+		sols, _ := Solve(Equation{c.Term, S(0)})
+		exact = append(exact, expand(sols, x)...)
+
+		prevX, prevOk := x.Lo, c.Holds(x.Lo)
+		for i := 1; i <= solveSamples; i++ {
+			v := x.Lo + x.Width()*float64(i)/solveSamples
+			ok := c.Holds(v)
+
+			if ok != prevOk {
+				found = append(found, change(c, prevX, v, prevOk))
+			}
+
+			prevX, prevOk = v, ok
+		}
+	}
+
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(a))
+	}
+
+	// Prefer exact zeros over numerically found points close to them
+	sort.Float64s(exact)
+	out := make([]float64, 0, len(exact)+len(found))
+	for _, p := range exact {
+		if len(out) == 0 || !near(out[len(out)-1], p) {
+			out = append(out, p)
+		}
+	}
+
+	for _, p := range found {
+		dup := false
+		for _, q := range out {
+			dup = dup || near(p, q)
+		}
+
+		if !dup {
+			out = append(out, p)
+		}
+	}
+
+	sort.Float64s(out)
+
+	return out
+}
+
+// expand lists the members of a set of solutions that lie in an interval
+func expand(sols []Solution, x Interval) []float64 {
+	out := make([]float64, 0, len(sols))
+
+	for _, sol := range sols {
+		if sol.Period == 0 {
+			if x.Contains(sol.Value) {
+				out = append(out, sol.Value)
+			}
+			continue
+		}
+
+		p := math.Abs(sol.Period)
+		for k := math.Ceil((x.Lo - sol.Value) / p); sol.Value+k*p <= x.Hi; k++ {
+			out = append(out, sol.Value+k*p)
+		}
+	}
+
+	return out
+}
+
+// change finds the point between a and b where a condition stops or starts holding, given whether it holds at a
+func change(c Condition, a, b float64, atA bool) float64 {
+	for i := 0; i < 100; i++ {
+		m := (a + b) / 2
+		if m == a || m == b {
+			break
+		}
+
+		if c.Holds(m) == atA {
+			a = m
+		} else {
+			b = m
+		}
+	}
+
+	return (a + b) / 2
+}
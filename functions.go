@@ -0,0 +1,156 @@
+package alg
+
+import (
+	"math"
+	"sync"
+)
+
+/*
+Functions lets you give a term in X a name, and apply it to other terms:
+  Call => A defined function applied to a term
+Calls tokenise as the function's name followed by the argument, and the name is kept in the token so that calls to different functions never share a key.
+A function must be defined before terms calling it are parsed, but calls to functions that aren't defined yet can still be built, printed and compared.
+Until then they evaluate to NaN, and EvalChecked reports them with ErrUndefined.
+*/
+
+func init() {
+	// Parse builds calls from the name in their token, like wildcards
+	register(TidCall, Registration{
+		Arity: 1,
+		Type:  Call{},
+	})
+}
+
+// Function is a named term in X, see Define
+type Function struct {
+	Name string
+	Body Term
+
+	// Inline makes T replace calls with the body, rather than keeping them
+	Inline bool
+}
+
+var (
+	functions   = make(map[string]Function)
+	functionsMu sync.RWMutex
+)
+
+// lookupFunction finds the function with a name
+func lookupFunction(name string) (Function, bool) {
+	functionsMu.RLock()
+	defer functionsMu.RUnlock()
+
+	f, ok := functions[name]
+	return f, ok
+}
+
+// Define names a term in X so that it can be used with Call, and registers the name as a token.
+// Like Register, it panics if the name is already taken, and should be called while initialising since it adds to the tokeniser.
+func Define(name string, body Term) Function {
+	return define(Function{Name: name, Body: body})
+}
+
+// DefineInline is like Define, but calls to the function are replaced with its body when simplified with T
+func DefineInline(name string, body Term) Function {
+	return define(Function{Name: name, Body: body, Inline: true})
+}
+
+func define(f Function) Function {
+	Register(Registration{
+		Name:  f.Name,
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Call{f.Name, args[0]}, nil
+		},
+	})
+
+	functionsMu.Lock()
+	functions[f.Name] = f
+	functionsMu.Unlock()
+
+	return f
+}
+
+// Of applies the function to a term
+func (f Function) Of(x Term) Call {
+	return Call{f.Name, x}
+}
+
+type Call struct {
+	Name string
+	X    Term
+}
+
+func (e Call) Tokenise() Tokens {
+	t := Tokens{{id: TidCall, name: e.Name}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Call) E(x float64) float64 {
+	f, ok := lookupFunction(e.Name)
+	if !ok {
+		return math.NaN()
+	}
+
+	return f.Body.E(e.X.E(x))
+}
+
+func (e Call) Dx() Term {
+	f, ok := lookupFunction(e.Name)
+	if !ok {
+		return S(math.NaN())
+	}
+
+	// The chain rule, with the derivative of the body written out in terms of the argument
+	return Prod{
+		e.X.Dx(),
+		Substitute(f.Body.Dx(), e.X),
+	}.T()
+}
+
+func (e Call) T() Term {
+	e = simplified(e)
+
+	if ok, val := e.Is(); ok {
+		return S(val)
+	}
+
+	if f, ok := lookupFunction(e.Name); ok && f.Inline {
+		return e.Inline().T()
+	}
+
+	return Call{e.Name, e.X}
+}
+
+func (e Call) Is() (bool, float64) {
+	f, ok := lookupFunction(e.Name)
+	if !ok {
+		return false, 0
+	}
+
+	if ok, val := e.X.Is(); ok {
+		return true, f.Body.E(val)
+	}
+
+	return false, 0
+}
+
+func (e Call) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Call) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+// Inline replaces the call with the function's body, with the argument in place of X
+func (e Call) Inline() Term {
+	f, ok := lookupFunction(e.Name)
+	if !ok {
+		return S(math.NaN())
+	}
+
+	return Substitute(f.Body, e.X)
+}
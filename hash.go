@@ -0,0 +1,79 @@
+package alg
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// Same reports whether two terms are structurally equal, which is when they tokenise the same way.
+// This makes TP{X{}, 2} equal to TPT{X{}, S(2)}, but doesn't simplify, so Add{X{}, S(0)} isn't equal to X{}.
+func Same(a, b Term) bool {
+	return key(a) == key(b)
+}
+
+// Hash returns a structural hash of a term, which is the same for any two terms that Same reports as equal.
+// It is an FNV-1a hash of the term's tokens, so it is stable between runs, as long as any terms from Register are registered in the same order.
+func Hash(term Term) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key(term)))
+	return h.Sum64()
+}
+
+// Interner keeps one canonical copy of each unique term, so that interned terms can be compared by pointer and share their subterms.
+// Terms are interned by their exact structure, so TP{X{}, 2} and TPT{X{}, S(2)} get different pointers even though Same reports them as equal.
+// It is safe for concurrent use.
+type Interner struct {
+	mu    sync.Mutex
+	terms map[string]*Term
+}
+
+// NewInterner returns an empty interner
+func NewInterner() *Interner {
+	return &Interner{
+		terms: make(map[string]*Term),
+	}
+}
+
+// Intern returns the canonical pointer for a term.
+// Its children are interned first, and the canonical term is built from their canonical copies.
+func (in *Interner) Intern(term Term) *Term {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return in.intern(term)
+}
+
+// Len returns the number of unique terms interned
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return len(in.terms)
+}
+
+func (in *Interner) intern(term Term) *Term {
+	// Like the nodes of a DAG, a term is identified by its own type and scalars, and the canonical pointers of its children
+	k := shape(term)
+
+	if p, ok := term.(Parent); ok {
+		children := p.Children()
+		canonical := make([]*Term, len(children))
+
+		for i, child := range children {
+			canonical[i] = in.intern(child)
+			children[i] = *canonical[i]
+		}
+
+		k += fmt.Sprint(canonical)
+		term = p.WithChildren(children)
+	}
+
+	if c, ok := in.terms[k]; ok {
+		return c
+	}
+
+	c := &term
+	in.terms[k] = c
+	return c
+}
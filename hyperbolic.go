@@ -12,6 +12,57 @@ Hyperbolic defines many trigonometric functions:
   Coth => Hyperbolic cotangent
 */
 
+func init() {
+	register(TidSinh, Registration{
+		Name:  "sinh",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sinh{X: args[0]}, nil
+		},
+		Type: Sinh{},
+	})
+	register(TidCosh, Registration{
+		Name:  "cosh",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Cosh{X: args[0]}, nil
+		},
+		Type: Cosh{},
+	})
+	register(TidTanh, Registration{
+		Name:  "tanh",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Tanh{X: args[0]}, nil
+		},
+		Type: Tanh{},
+	})
+	register(TidSech, Registration{
+		Name:  "sech",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sech{X: args[0]}, nil
+		},
+		Type: Sech{},
+	})
+	register(TidCsch, Registration{
+		Name:  "csch",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Csch{X: args[0]}, nil
+		},
+		Type: Csch{},
+	})
+	register(TidCoth, Registration{
+		Name:  "coth",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Coth{X: args[0]}, nil
+		},
+		Type: Coth{},
+	})
+}
+
 type Sinh struct {
 	X Term
 }
@@ -30,13 +81,20 @@ func (e Sinh) Dx() Term {
 }
 
 func (e Sinh) T() Term {
+	e = simplified(e)
+
 	ok, v := e.X.Is()
 
 	if ok {
 		return S(math.Sinh(v))
 	}
 
-	return Sinh{e.X.T()}
+	// sinh(asinh(x)) = x for every x
+	if inv, ok := e.X.(Asinh); ok {
+		return inv.X.T()
+	}
+
+	return Sinh{e.X}
 }
 
 func (e Sinh) Is() (bool, float64) {
@@ -49,6 +107,15 @@ func (e Sinh) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Sinh) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Sinh) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Sinh) Tokenise() Tokens {
 	t := Tokens{{id: TidSinh}}
 	t = append(t, e.X.Tokenise()...)
@@ -73,13 +140,20 @@ func (e Cosh) Dx() Term {
 }
 
 func (e Cosh) T() Term {
+	e = simplified(e)
+
 	ok, v := e.X.Is()
 
 	if ok {
 		return S(math.Cosh(v))
 	}
 
-	return Cosh{e.X.T()}
+	// cosh(acosh(x)) = x wherever acosh(x) is defined, which is only for x >= 1
+	if inv, ok := e.X.(Acosh); ok {
+		return inv.X.T()
+	}
+
+	return Cosh{e.X}
 }
 
 func (e Cosh) Is() (bool, float64) {
@@ -92,6 +166,15 @@ func (e Cosh) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Cosh) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Cosh) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Cosh) Tokenise() Tokens {
 	t := Tokens{{id: TidCosh}}
 	t = append(t, e.X.Tokenise()...)
@@ -117,12 +200,19 @@ func (e Tanh) Dx() Term {
 }
 
 func (e Tanh) T() Term {
+	e = simplified(e)
+
 	ok, v := e.X.Is()
 	if ok {
 		return S(math.Tanh(v))
 	}
 
-	return Tanh{e.X.T()}
+	// tanh(atanh(x)) = x wherever atanh(x) is defined, which is only in (-1, 1)
+	if inv, ok := e.X.(Atanh); ok {
+		return inv.X.T()
+	}
+
+	return Tanh{e.X}
 }
 
 func (e Tanh) Is() (bool, float64) {
@@ -134,6 +224,15 @@ func (e Tanh) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Tanh) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Tanh) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Tanh) Tokenise() Tokens {
 	t := Tokens{{id: TidTanh}}
 	t = append(t, e.X.Tokenise()...)
@@ -160,12 +259,19 @@ func (e Coth) Dx() Term {
 }
 
 func (e Coth) T() Term {
+	e = simplified(e)
+
 	ok, v := e.X.Is()
 	if ok {
 		return S(1 / math.Tanh(v))
 	}
 
-	return Coth{e.X.T()}
+	// coth(acoth(x)) = x wherever acoth(x) is defined, which is only outside [-1, 1]
+	if inv, ok := e.X.(Acoth); ok {
+		return inv.X.T()
+	}
+
+	return Coth{e.X}
 }
 
 func (e Coth) Is() (bool, float64) {
@@ -177,6 +283,15 @@ func (e Coth) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Coth) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Coth) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Coth) Tokenise() Tokens {
 	t := Tokens{{id: TidCoth}}
 	t = append(t, e.X.Tokenise()...)
@@ -201,12 +316,19 @@ func (e Sech) Dx() Term {
 }
 
 func (e Sech) T() Term {
+	e = simplified(e)
+
 	ok, v := e.X.Is()
 	if ok {
 		return S(1 / math.Cosh(v))
 	}
 
-	return Sech{e.X.T()}
+	// sech(asech(x)) = x wherever asech(x) is defined, which is only in (0, 1]
+	if inv, ok := e.X.(Asech); ok {
+		return inv.X.T()
+	}
+
+	return Sech{e.X}
 }
 
 func (e Sech) Is() (bool, float64) {
@@ -218,6 +340,15 @@ func (e Sech) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Sech) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Sech) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Sech) Tokenise() Tokens {
 	t := Tokens{{id: TidSech}}
 	t = append(t, e.X.Tokenise()...)
@@ -242,12 +373,19 @@ func (e Csch) Dx() Term {
 }
 
 func (e Csch) T() Term {
+	e = simplified(e)
+
 	ok, v := e.X.Is()
 	if ok {
 		return S(1 / math.Sinh(v))
 	}
 
-	return Csch{e.X.T()}
+	// csch(acsch(x)) = x wherever acsch(x) is defined, which is every x but 0
+	if inv, ok := e.X.(Acsch); ok {
+		return inv.X.T()
+	}
+
+	return Csch{e.X}
 }
 
 func (e Csch) Is() (bool, float64) {
@@ -259,6 +397,15 @@ func (e Csch) Is() (bool, float64) {
 	return false, 0
 }
 
+func (e Csch) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Csch) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 func (e Csch) Tokenise() Tokens {
 	t := Tokens{{id: TidCsch}}
 	t = append(t, e.X.Tokenise()...)
@@ -0,0 +1,522 @@
+package alg
+
+import (
+	"math"
+)
+
+// Interval is a closed range of numbers [Lo, Hi].
+// Bounds are NaN when a term is undefined everywhere in its input.
+type Interval struct {
+	Lo, Hi float64
+}
+
+// entire is the interval containing every number
+var entire = Interval{math.Inf(-1), math.Inf(1)}
+
+// Contains returns true if v lies in the interval
+func (i Interval) Contains(v float64) bool {
+	return i.Lo <= v && v <= i.Hi
+}
+
+// Width returns the distance between the bounds of the interval
+func (i Interval) Width() float64 {
+	return i.Hi - i.Lo
+}
+
+// EvalInterval returns an interval guaranteed to contain the value of term at every x in the input interval.
+// Bounds are rounded outwards after each operation, so the enclosure also holds under floating point rounding.
+// Conditional terms take the union of both branches unless the condition is decided over the whole interval.
+func EvalInterval(term Term, x Interval) Interval {
+	if x.Lo > x.Hi || math.IsNaN(x.Lo) || math.IsNaN(x.Hi) {
+		return Interval{math.NaN(), math.NaN()}
+	}
+
+	switch e := term.(type) {
+	case S:
+		return Interval{float64(e), float64(e)}
+	case X:
+		return x
+	case Pi, EulerE, Phi:
+		return outward(term.E(0), term.E(0))
+	case Sx:
+		return mulI(Interval{e.S, e.S}, x)
+	case Sum:
+		out := Interval{0, 0}
+		for _, sub := range e {
+			out = addI(out, EvalInterval(sub, x))
+		}
+		return out
+	case Prod:
+		out := Interval{1, 1}
+		for _, sub := range e {
+			out = mulI(out, EvalInterval(sub, x))
+		}
+		return out
+	case Add:
+		return addI(EvalInterval(e.A, x), EvalInterval(e.B, x))
+	case Sub:
+		return addI(EvalInterval(e.A, x), negI(EvalInterval(e.B, x)))
+	case Mul:
+		return mulI(EvalInterval(e.A, x), EvalInterval(e.B, x))
+	case Div:
+		return divI(EvalInterval(e.N, x), EvalInterval(e.D, x))
+	case Exp:
+		return expI(EvalInterval(e.X, x))
+	case Ln:
+		return lnI(EvalInterval(e.X, x))
+	case Log:
+		return divI(lnI(EvalInterval(e.X, x)), lnI(EvalInterval(e.Base, x)))
+	case Log2:
+		return clampedI(math.Log2, EvalInterval(e.X, x), 0, math.Inf(1))
+	case Log10:
+		return clampedI(math.Log10, EvalInterval(e.X, x), 0, math.Inf(1))
+	case Sqrt:
+		return clampedI(math.Sqrt, EvalInterval(e.X, x), 0, math.Inf(1))
+	case Cbrt:
+		return monotoneI(math.Cbrt, EvalInterval(e.X, x))
+	case TP:
+		return powI(EvalInterval(e.X, x), e.P)
+	case PT:
+		return ptI(e.V, EvalInterval(e.X, x))
+	case TPT:
+		if ok, v := e.B.Is(); ok {
+			return powI(EvalInterval(e.A, x), v)
+		} else if ok, v := e.A.Is(); ok {
+			return ptI(v, EvalInterval(e.B, x))
+		}
+
+		// Negative bases give real values for integer powers, which exp(b ln a) would miss
+		a := EvalInterval(e.A, x)
+		if a.Lo < 0 {
+			return entire
+		}
+		return expI(mulI(EvalInterval(e.B, x), lnI(a)))
+	case Sin:
+		return sinI(EvalInterval(e.X, x))
+	case Cos:
+		return sinI(addI(EvalInterval(e.X, x), Interval{math.Pi / 2, math.Pi / 2}))
+	case Tan:
+		return tanI(EvalInterval(e.X, x))
+	case Sec:
+		return divI(Interval{1, 1}, sinI(addI(EvalInterval(e.X, x), Interval{math.Pi / 2, math.Pi / 2})))
+	case Csc:
+		return divI(Interval{1, 1}, sinI(EvalInterval(e.X, x)))
+	case Cot:
+		return negI(tanI(addI(EvalInterval(e.X, x), Interval{math.Pi / 2, math.Pi / 2})))
+	case Sinh:
+		return monotoneI(math.Sinh, EvalInterval(e.X, x))
+	case Cosh:
+		return coshI(EvalInterval(e.X, x))
+	case Tanh:
+		return monotoneI(math.Tanh, EvalInterval(e.X, x))
+	case Sech:
+		return divI(Interval{1, 1}, coshI(EvalInterval(e.X, x)))
+	case Csch:
+		return divI(Interval{1, 1}, monotoneI(math.Sinh, EvalInterval(e.X, x)))
+	case Coth:
+		return divI(Interval{1, 1}, monotoneI(math.Tanh, EvalInterval(e.X, x)))
+	case Asin:
+		return clampedI(math.Asin, EvalInterval(e.X, x), -1, 1)
+	case Acos:
+		return clampedI(math.Acos, EvalInterval(e.X, x), -1, 1)
+	case Atan:
+		return monotoneI(math.Atan, EvalInterval(e.X, x))
+	case Asec:
+		return clampedI(math.Acos, divI(Interval{1, 1}, EvalInterval(e.X, x)), -1, 1)
+	case Acsc:
+		return clampedI(math.Asin, divI(Interval{1, 1}, EvalInterval(e.X, x)), -1, 1)
+	case Acot:
+		return clampedI(acot, EvalInterval(e.X, x), math.Inf(-1), math.Inf(1))
+	case Atan2:
+		// Only the range is used, since the angle jumps across the negative x axis
+		return outward(-math.Pi, math.Pi)
+	case Asinh:
+		return monotoneI(math.Asinh, EvalInterval(e.X, x))
+	case Acosh:
+		return clampedI(math.Acosh, EvalInterval(e.X, x), 1, math.Inf(1))
+	case Atanh:
+		return clampedI(math.Atanh, EvalInterval(e.X, x), -1, 1)
+	case Asech:
+		return clampedI(asech, EvalInterval(e.X, x), 0, 1)
+	case Acsch:
+		return monotoneI(math.Asinh, divI(Interval{1, 1}, EvalInterval(e.X, x)))
+	case Acoth:
+		return clampedI(math.Atanh, divI(Interval{1, 1}, EvalInterval(e.X, x)), -1, 1)
+	case Abs:
+		return absI(EvalInterval(e.X, x))
+	case Sign:
+		a := EvalInterval(e.X, x)
+		return Interval{sgn(a.Lo), sgn(a.Hi)}
+	case Min:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return Interval{math.Min(a.Lo, b.Lo), math.Min(a.Hi, b.Hi)}
+	case Max:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return Interval{math.Max(a.Lo, b.Lo), math.Max(a.Hi, b.Hi)}
+	case Floor:
+		a := EvalInterval(e.X, x)
+		return Interval{math.Floor(a.Lo), math.Floor(a.Hi)}
+	case Ceil:
+		a := EvalInterval(e.X, x)
+		return Interval{math.Ceil(a.Lo), math.Ceil(a.Hi)}
+	case Mod:
+		return modI(EvalInterval(e.A, x), EvalInterval(e.B, x))
+	case Erf:
+		return monotoneI(math.Erf, EvalInterval(e.X, x))
+	case Erfc:
+		return clampedI(math.Erfc, EvalInterval(e.X, x), math.Inf(-1), math.Inf(1))
+	case Gamma:
+		return gammaI(gamma, EvalInterval(e.X, x))
+	case LogGamma:
+		return gammaI(lgamma, EvalInterval(e.X, x))
+	case Digamma:
+		// Digamma increases between each pair of poles, but only the last stretch is handled
+		if a := EvalInterval(e.X, x); a.Lo > 0 {
+			return monotoneI(func(v float64) float64 { return polygamma(0, v) }, a)
+		}
+	case Polygamma:
+		if a := EvalInterval(e.X, x); a.Lo > 0 && e.N >= 0 {
+			return clampedI(func(v float64) float64 { return polygamma(e.N, v) }, a, 0, math.Inf(1))
+		}
+	case BesselJ:
+		// Bessel functions of the first kind never leave [-1, 1]
+		return Interval{-1, 1}
+	case Call:
+		return EvalInterval(e.Inline(), x)
+	case Greater, Less, GreaterEqual, LessEqual, Equal, NotEqual, Range:
+		ifTrue, ifFalse, _, _ := branches(term)
+		always, never := decide(term, x)
+		return branchI(always, never, ifTrue, ifFalse, x)
+	}
+
+	// Terms this doesn't know about can still be bounded by everything
+	return entire
+}
+
+// IsolateRoots splits an interval into pieces no wider than tol that may contain a root of term.
+// Every root in the interval is guaranteed to lie in one of the returned pieces, though not every piece need contain a root.
+func IsolateRoots(term Term, x Interval, tol float64) []Interval {
+	out := make([]Interval, 0)
+
+	var split func(x Interval)
+	split = func(x Interval) {
+		if v := EvalInterval(term, x); !v.Contains(0) && !math.IsNaN(v.Lo) {
+			return
+		}
+
+		mid := (x.Lo + x.Hi) / 2
+		if x.Width() <= tol || mid == x.Lo || mid == x.Hi {
+			// Merge touching pieces, so a root on a boundary isn't reported twice
+			if n := len(out); n > 0 && out[n-1].Hi == x.Lo {
+				out[n-1].Hi = x.Hi
+			} else {
+				out = append(out, x)
+			}
+			return
+		}
+
+		split(Interval{x.Lo, mid})
+		split(Interval{mid, x.Hi})
+	}
+
+	split(x)
+
+	return out
+}
+
+// outward rounds the bounds of an interval away from each other by one unit in the last place
+func outward(lo, hi float64) Interval {
+	if math.IsNaN(lo) || math.IsNaN(hi) {
+		return Interval{math.NaN(), math.NaN()}
+	}
+
+	return Interval{math.Nextafter(lo, math.Inf(-1)), math.Nextafter(hi, math.Inf(1))}
+}
+
+// hull returns the smallest interval containing both intervals
+func hull(a, b Interval) Interval {
+	if math.IsNaN(a.Lo) {
+		return b
+	} else if math.IsNaN(b.Lo) {
+		return a
+	}
+
+	return Interval{math.Min(a.Lo, b.Lo), math.Max(a.Hi, b.Hi)}
+}
+
+func addI(a, b Interval) Interval {
+	return outward(a.Lo+b.Lo, a.Hi+b.Hi)
+}
+
+func negI(a Interval) Interval {
+	return Interval{-a.Hi, -a.Lo}
+}
+
+func mulI(a, b Interval) Interval {
+	// 0 * inf is taken to be 0, since the infinite bound is never reached
+	m := func(x, y float64) float64 {
+		if x == 0 || y == 0 {
+			return 0
+		}
+		return x * y
+	}
+
+	p := []float64{m(a.Lo, b.Lo), m(a.Lo, b.Hi), m(a.Hi, b.Lo), m(a.Hi, b.Hi)}
+
+	lo, hi := p[0], p[0]
+	for _, v := range p[1:] {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+
+	return outward(lo, hi)
+}
+
+func divI(a, b Interval) Interval {
+	if b.Lo == 0 && b.Hi == 0 {
+		return Interval{math.NaN(), math.NaN()}
+	}
+
+	// Only one side of a divisor that touches zero is used, so its reciprocal is unbounded in one direction
+	if b.Lo == 0 {
+		return mulI(a, Interval{math.Nextafter(1/b.Hi, math.Inf(-1)), math.Inf(1)})
+	} else if b.Hi == 0 {
+		return mulI(a, Interval{math.Inf(-1), math.Nextafter(1/b.Lo, math.Inf(1))})
+	} else if b.Contains(0) {
+		return entire
+	}
+
+	return mulI(a, outward(1/b.Hi, 1/b.Lo))
+}
+
+func expI(a Interval) Interval {
+	return monotoneI(math.Exp, a)
+}
+
+func lnI(a Interval) Interval {
+	if a.Hi < 0 {
+		return Interval{math.NaN(), math.NaN()}
+	}
+
+	return monotoneI(math.Log, Interval{math.Max(a.Lo, 0), a.Hi})
+}
+
+// gammaMin is where gamma has its minimum over the positive numbers
+const gammaMin = 1.4616321449683623
+
+// gammaI bounds gamma or log gamma, which both fall until gammaMin and then rise, for positive arguments
+func gammaI(f func(float64) float64, a Interval) Interval {
+	if a.Lo <= 0 {
+		return entire
+	}
+
+	if a.Lo >= gammaMin || a.Hi <= gammaMin {
+		return clampedI(f, a, 0, math.Inf(1))
+	}
+
+	return outward(f(gammaMin), math.Max(f(a.Lo), f(a.Hi)))
+}
+
+// monotoneI applies an increasing function to both bounds
+func monotoneI(f func(float64) float64, a Interval) Interval {
+	return outward(f(a.Lo), f(a.Hi))
+}
+
+// clampedI applies a monotone function that is only defined on [lo, hi] to the part of the interval it is defined on
+func clampedI(f func(float64) float64, a Interval, lo, hi float64) Interval {
+	if a.Hi < lo || a.Lo > hi {
+		return Interval{math.NaN(), math.NaN()}
+	}
+
+	l, h := f(math.Max(a.Lo, lo)), f(math.Min(a.Hi, hi))
+	return outward(math.Min(l, h), math.Max(l, h))
+}
+
+func powI(a Interval, p float64) Interval {
+	if p == 0 {
+		return Interval{1, 1}
+	}
+
+	if p != math.Trunc(p) {
+		// Non-integer powers are only defined for non-negative bases
+		if a.Hi < 0 {
+			return Interval{math.NaN(), math.NaN()}
+		}
+		a.Lo = math.Max(a.Lo, 0)
+
+		if p > 0 {
+			return outward(math.Pow(a.Lo, p), math.Pow(a.Hi, p))
+		}
+		return outward(math.Pow(a.Hi, p), math.Pow(a.Lo, p))
+	}
+
+	if p < 0 {
+		return divI(Interval{1, 1}, powI(a, -p))
+	}
+
+	if math.Mod(p, 2) != 0 {
+		return outward(math.Pow(a.Lo, p), math.Pow(a.Hi, p))
+	}
+
+	// Even powers are smallest at the bound closest to zero
+	if a.Contains(0) {
+		return outward(0, math.Max(math.Pow(a.Lo, p), math.Pow(a.Hi, p)))
+	} else if a.Lo > 0 {
+		return outward(math.Pow(a.Lo, p), math.Pow(a.Hi, p))
+	}
+
+	return outward(math.Pow(a.Hi, p), math.Pow(a.Lo, p))
+}
+
+func ptI(v float64, a Interval) Interval {
+	if v <= 0 {
+		return entire
+	} else if v < 1 {
+		return outward(math.Pow(v, a.Hi), math.Pow(v, a.Lo))
+	}
+
+	return outward(math.Pow(v, a.Lo), math.Pow(v, a.Hi))
+}
+
+func coshI(a Interval) Interval {
+	if a.Contains(0) {
+		return outward(1, math.Max(math.Cosh(a.Lo), math.Cosh(a.Hi)))
+	}
+
+	return outward(math.Min(math.Cosh(a.Lo), math.Cosh(a.Hi)), math.Max(math.Cosh(a.Lo), math.Cosh(a.Hi)))
+}
+
+func absI(a Interval) Interval {
+	if a.Lo >= 0 {
+		return a
+	} else if a.Hi <= 0 {
+		return negI(a)
+	}
+
+	return Interval{0, math.Max(-a.Lo, a.Hi)}
+}
+
+// modI bounds a mod b, which is in [0, b) for positive b and (b, 0] for negative b
+func modI(a, b Interval) Interval {
+	if b.Contains(0) || math.IsNaN(a.Lo) || math.IsNaN(b.Lo) {
+		return entire
+	}
+
+	// With a constant divisor, a doesn't wrap around if it stays between two multiples of b
+	if b.Lo == b.Hi && !math.IsInf(a.Lo, 0) && !math.IsInf(a.Hi, 0) {
+		n := math.Floor(a.Lo / b.Lo)
+		if math.Floor(a.Hi/b.Lo) == n {
+			return outward(a.Lo-n*b.Lo, a.Hi-n*b.Lo)
+		}
+	}
+
+	if b.Lo > 0 {
+		return Interval{0, b.Hi}
+	}
+	return Interval{b.Lo, 0}
+}
+
+// sinI bounds sin over an interval, checking whether it contains a peak or trough
+func sinI(a Interval) Interval {
+	if math.IsInf(a.Lo, 0) || math.IsInf(a.Hi, 0) || a.Width() >= 2*math.Pi {
+		return Interval{-1, 1}
+	}
+
+	lo := math.Min(math.Sin(a.Lo), math.Sin(a.Hi))
+	hi := math.Max(math.Sin(a.Lo), math.Sin(a.Hi))
+
+	// The peaks are at pi/2 + 2k pi and the troughs at -pi/2 + 2k pi
+	if contains(a, math.Pi/2, 2*math.Pi) {
+		hi = 1
+	}
+	if contains(a, -math.Pi/2, 2*math.Pi) {
+		lo = -1
+	}
+
+	out := outward(lo, hi)
+	return Interval{math.Max(out.Lo, -1), math.Min(out.Hi, 1)}
+}
+
+// tanI bounds tan over an interval, which is unbounded if the interval contains one of its poles
+func tanI(a Interval) Interval {
+	if math.IsInf(a.Lo, 0) || math.IsInf(a.Hi, 0) || a.Width() >= math.Pi {
+		return entire
+	}
+
+	// The poles are at pi/2 + k pi
+	if contains(a, math.Pi/2, math.Pi) {
+		return entire
+	}
+
+	return outward(math.Tan(a.Lo), math.Tan(a.Hi))
+}
+
+// contains returns true if the interval might contain a point offset + k period for some integer k.
+// The interval is widened slightly first, since pi is not exact.
+func contains(a Interval, offset, period float64) bool {
+	eps := 1e-14 * math.Max(1, math.Max(math.Abs(a.Lo), math.Abs(a.Hi)))
+
+	return math.Ceil((a.Lo-eps-offset)/period) <= math.Floor((a.Hi+eps-offset)/period)
+}
+
+// decide works out whether the condition of a conditional term is always or never true over an interval.
+// Both are false if it could go either way.
+func decide(term Term, x Interval) (always, never bool) {
+	switch e := term.(type) {
+	case Greater:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return a.Lo > b.Hi, a.Hi <= b.Lo
+	case Less:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return a.Hi < b.Lo, a.Lo >= b.Hi
+	case GreaterEqual:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return a.Lo >= b.Hi, a.Hi < b.Lo
+	case LessEqual:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return a.Hi <= b.Lo, a.Lo > b.Hi
+	case Equal:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return a.Lo == a.Hi && b.Lo == b.Hi && a.Lo == b.Lo, a.Hi < b.Lo || a.Lo > b.Hi
+	case NotEqual:
+		a, b := EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return a.Hi < b.Lo || a.Lo > b.Hi, a.Lo == a.Hi && b.Lo == b.Hi && a.Lo == b.Lo
+	case Range:
+		v, a, b := EvalInterval(e.X, x), EvalInterval(e.A, x), EvalInterval(e.B, x)
+		return v.Lo >= a.Hi && v.Hi <= b.Lo, v.Hi < a.Lo || v.Lo > b.Hi || a.Lo > b.Hi
+	}
+
+	return false, false
+}
+
+// branches splits a conditional term into its two branches, and a function that rebuilds it with new branches
+func branches(term Term) (Term, Term, func(ifTrue, ifFalse Term) Term, bool) {
+	switch e := term.(type) {
+	case Greater:
+		return e.If, e.Else, func(a, b Term) Term { return Greater{e.A, e.B, a, b} }, true
+	case Less:
+		return e.If, e.Else, func(a, b Term) Term { return Less{e.A, e.B, a, b} }, true
+	case GreaterEqual:
+		return e.If, e.Else, func(a, b Term) Term { return GreaterEqual{e.A, e.B, a, b} }, true
+	case LessEqual:
+		return e.If, e.Else, func(a, b Term) Term { return LessEqual{e.A, e.B, a, b} }, true
+	case Equal:
+		return e.If, e.Else, func(a, b Term) Term { return Equal{e.A, e.B, a, b} }, true
+	case NotEqual:
+		return e.If, e.Else, func(a, b Term) Term { return NotEqual{e.A, e.B, a, b} }, true
+	case Range:
+		return e.If, e.Else, func(a, b Term) Term { return Range{e.X, e.A, e.B, a, b} }, true
+	}
+
+	return nil, nil, nil, false
+}
+
+// branchI bounds a conditional, given whether its condition is always or never true over the interval
+func branchI(always, never bool, ifTrue, ifFalse Term, x Interval) Interval {
+	if always {
+		return EvalInterval(ifTrue, x)
+	} else if never {
+		return EvalInterval(ifFalse, x)
+	}
+
+	return hull(EvalInterval(ifTrue, x), EvalInterval(ifFalse, x))
+}
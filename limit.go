@@ -0,0 +1,569 @@
+package alg
+
+import (
+	"errors"
+	"math"
+)
+
+// Direction is the side a limit is approached from
+type Direction int
+
+const (
+	FromBoth Direction = iota
+	FromBelow
+	FromAbove
+)
+
+// LimitResult is the value of a limit, along with how it was found.
+// If the limit could not be decided symbolically it is extrapolated numerically, Numerical is set and ErrEst estimates the error.
+type LimitResult struct {
+	Value     float64
+	Numerical bool
+	ErrEst    float64
+}
+
+// numericalTol is the largest error estimate that a numerically extrapolated limit is accepted with, relative to the size of the limit.
+// Terms with logs or fractional powers near the limit converge slowly, so it is loose, while oscillating terms have errors close to their size.
+const numericalTol = 1e-3
+
+// maxLHopital is the most times l'Hôpital's rule is applied before giving up on a limit
+const maxLHopital = 10
+
+// Limit finds the limit of a term as x approaches x0, which may be infinite.
+// Indeterminate fractions are resolved with l'Hôpital's rule, and indeterminate sums at infinity by finding their dominant term.
+func Limit(term Term, x0 float64, dir Direction) (LimitResult, error) {
+	if math.IsNaN(x0) {
+		return LimitResult{}, errors.New("limit point is NaN")
+	}
+
+	if math.IsInf(x0, 1) {
+		return limitSide(term, x0, -1)
+	} else if math.IsInf(x0, -1) {
+		return limitSide(term, x0, 1)
+	}
+
+	switch dir {
+	case FromBelow:
+		return limitSide(term, x0, -1)
+	case FromAbove:
+		return limitSide(term, x0, 1)
+	}
+
+	below, err := limitSide(term, x0, -1)
+	if err != nil {
+		return LimitResult{}, err
+	}
+
+	above, err := limitSide(term, x0, 1)
+	if err != nil {
+		return LimitResult{}, err
+	}
+
+	tol := math.Max(below.ErrEst, above.ErrEst) + 1e-9*math.Max(1, math.Abs(below.Value))
+	if below.Value != above.Value && (math.IsInf(below.Value, 0) || !(math.Abs(below.Value-above.Value) <= tol)) {
+		return LimitResult{}, errors.New("limits from below and above differ")
+	}
+
+	return LimitResult{
+		Value:     (below.Value + above.Value) / 2,
+		Numerical: below.Numerical || above.Numerical,
+		ErrEst:    math.Max(below.ErrEst, above.ErrEst),
+	}, nil
+}
+
+// limitSide finds the limit approaching x0 from one side, side is -1 for below and 1 for above.
+func limitSide(term Term, x0, side float64) (LimitResult, error) {
+	l := limiter{
+		x0:    x0,
+		side:  side,
+		cache: NewDerivativeCache(),
+	}
+
+	if v, ok := l.lim(term); ok {
+		return LimitResult{Value: v}, nil
+	}
+
+	v, errEst := l.numerical(term)
+	if math.IsNaN(v) {
+		return LimitResult{}, errors.New("limit could not be found")
+	} else if !(errEst <= numericalTol*math.Max(1, math.Abs(v))) {
+		// Terms that oscillate, like sin(x) at infinity, never settle down, so the extrapolation doesn't converge
+		return LimitResult{}, errors.New("limit does not converge")
+	}
+
+	return LimitResult{
+		Value:     v,
+		Numerical: true,
+		ErrEst:    errEst,
+	}, nil
+}
+
+// limiter holds the state of a single one-sided limit
+type limiter struct {
+	x0, side float64
+	cache    *DerivativeCache
+	depth    int
+}
+
+// near returns a point close to x0 on the side being approached from
+func (l *limiter) near() float64 {
+	if math.IsInf(l.x0, 0) {
+		return -l.side * 1e12
+	}
+
+	return l.x0 + l.side*1e-9*math.Max(1, math.Abs(l.x0))
+}
+
+// lim symbolically finds the limit of a term, returning false if it cannot be decided.
+func (l *limiter) lim(term Term) (float64, bool) {
+	switch e := term.(type) {
+	case Div:
+		return l.div(e.N, e.D)
+	case Mul:
+		return l.prod(Prod{e.A, e.B})
+	case Prod:
+		return l.prod(e)
+	case Add:
+		return l.sum(Sum{e.A, e.B})
+	case Sub:
+		return l.sum(Sum{e.A, Prod{S(-1), e.B}})
+	case Sum:
+		return l.sum(e)
+	case TPT:
+		return l.pow(e.A, e.B)
+	case TP:
+		return l.pow(e.X, S(e.P))
+	case PT:
+		return l.pow(S(e.V), e.X)
+	case Greater, Less, GreaterEqual, LessEqual, Equal, NotEqual, Range:
+		return l.lim(l.branch(term))
+	case Call:
+		return l.lim(e.Inline())
+	case Min:
+		return l.pair(e.A, e.B, math.Min)
+	case Max:
+		return l.pair(e.A, e.B, math.Max)
+	case Beta:
+		return l.pair(e.A, e.B, beta)
+	case Log:
+		return l.pair(e.Base, e.X, func(b, a float64) float64 { return math.Log(a) / math.Log(b) })
+	case Sign:
+		v, ok := l.lim(e.X)
+		if !ok || v != 0 {
+			return sgn(v), ok
+		}
+		return l.beside(term)
+	case Floor:
+		v, ok := l.lim(e.X)
+		if !ok || v != math.Trunc(v) || math.IsInf(v, 0) {
+			return math.Floor(v), ok
+		}
+		return l.beside(term)
+	case Ceil:
+		v, ok := l.lim(e.X)
+		if !ok || v != math.Trunc(v) || math.IsInf(v, 0) {
+			return math.Ceil(v), ok
+		}
+		return l.beside(term)
+	case Mod:
+		a, ok := l.lim(e.A)
+		if !ok {
+			return 0, false
+		}
+
+		b, ok := l.lim(e.B)
+		if !ok || b == 0 || math.IsInf(a, 0) || math.IsInf(b, 0) {
+			return 0, false
+		}
+
+		if q := a / b; q != math.Trunc(q) {
+			return mod(a, b), true
+		}
+
+		// At a jump the remainder tends to either 0 or b, depending on the side
+		v, ok := l.beside(term)
+		if math.Abs(v) > math.Abs(b)/2 {
+			return b, ok
+		}
+		return 0, ok
+	}
+
+	if arg, rebuild, ok := unary(term); ok {
+		v, ok := l.lim(arg)
+		if !ok {
+			return 0, false
+		}
+
+		// Poles that can't be represented exactly, like tan at pi/2, still give finite values, so check for them explicitly
+		out, err := EvalChecked(rebuild(S(v)), 0)
+		if near := term.E(l.near()); errors.Is(err, ErrPole) && !math.IsNaN(near) {
+			return math.Inf(sign(near)), true
+		}
+
+		return out, !math.IsNaN(out)
+	}
+
+	v := term.E(l.x0)
+	return v, !math.IsNaN(v)
+}
+
+// div finds the limit of a fraction, applying l'Hôpital's rule to indeterminate forms.
+func (l *limiter) div(n, d Term) (float64, bool) {
+	nv, ok := l.lim(n)
+	if !ok {
+		return 0, false
+	}
+
+	dv, ok := l.lim(d)
+	if !ok {
+		return 0, false
+	}
+
+	if nv == 0 && dv == 0 || math.IsInf(nv, 0) && math.IsInf(dv, 0) {
+		if l.depth >= maxLHopital {
+			return 0, false
+		}
+
+		l.depth++
+		defer func() { l.depth-- }()
+
+		return l.lim(ratio(l.cache.Dx(n), l.cache.Dx(d)))
+	}
+
+	if dv == 0 {
+		return math.Inf(sign(nv) * sign(d.E(l.near()))), true
+	}
+
+	return nv / dv, true
+}
+
+// prod finds the limit of a product, rewriting 0 * inf as a fraction.
+func (l *limiter) prod(p Prod) (float64, bool) {
+	zero, inf := -1, -1
+
+	var total float64 = 1
+	for i, term := range p {
+		v, ok := l.lim(term)
+		if !ok {
+			return 0, false
+		}
+
+		if v == 0 {
+			zero = i
+		} else if math.IsInf(v, 0) {
+			inf = i
+		}
+
+		total *= v
+	}
+
+	if zero == -1 || inf == -1 {
+		return total, true
+	}
+
+	// Try both 0 / (1 / inf) and inf / (1 / 0), since l'Hôpital's rule often only simplifies one of them
+	for _, i := range []int{zero, inf} {
+		rest := make(Prod, 0, len(p)-1)
+		rest = append(rest, p[:i]...)
+		rest = append(rest, p[i+1:]...)
+
+		if v, ok := l.div(p[i], Div{S(1), rest}); ok {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
+
+// sum finds the limit of a sum, comparing the growth of the terms when infinities cancel.
+func (l *limiter) sum(s Sum) (float64, bool) {
+	vals := make([]float64, len(s))
+	pos, neg := false, false
+
+	var total float64
+	for i, term := range s {
+		v, ok := l.lim(term)
+		if !ok {
+			return 0, false
+		}
+
+		pos = pos || math.IsInf(v, 1)
+		neg = neg || math.IsInf(v, -1)
+		vals[i] = v
+		total += v
+	}
+
+	if !pos || !neg {
+		return total, true
+	}
+
+	// Divide through by each infinite term in turn, until one dominates the rest
+	for i, term := range s {
+		if !math.IsInf(vals[i], 0) {
+			continue
+		}
+
+		var ratio float64
+		dominant := true
+		for j, other := range s {
+			if i == j {
+				ratio++
+				continue
+			}
+
+			r, ok := l.div(other, term)
+			if !ok || math.IsInf(r, 0) {
+				dominant = false
+				break
+			}
+
+			ratio += r
+		}
+
+		if dominant && ratio != 0 {
+			return math.Inf(sign(vals[i]) * sign(ratio)), true
+		}
+	}
+
+	return 0, false
+}
+
+// pow finds the limit of a to the power of b, rewriting indeterminate powers as exponentials.
+func (l *limiter) pow(a, b Term) (float64, bool) {
+	av, ok := l.lim(a)
+	if !ok {
+		return 0, false
+	}
+
+	bv, ok := l.lim(b)
+	if !ok {
+		return 0, false
+	}
+
+	if av == 0 && bv == 0 || av == 1 && math.IsInf(bv, 0) || math.IsInf(av, 1) && bv == 0 {
+		v, ok := l.prod(Prod{b, Ln{a}})
+		if !ok {
+			return 0, false
+		}
+
+		return math.Exp(v), true
+	}
+
+	v := math.Pow(av, bv)
+	return v, !math.IsNaN(v)
+}
+
+// pair finds the limit of a continuous function of two terms
+func (l *limiter) pair(a, b Term, f func(a, b float64) float64) (float64, bool) {
+	av, ok := l.lim(a)
+	if !ok {
+		return 0, false
+	}
+
+	bv, ok := l.lim(b)
+	if !ok {
+		return 0, false
+	}
+
+	return f(av, bv), true
+}
+
+// beside finds the limit of a piecewise constant term at one of its jumps, by evaluating it just to the side of x0
+func (l *limiter) beside(term Term) (float64, bool) {
+	v := term.E(l.near())
+	return v, !math.IsNaN(v)
+}
+
+// branch picks the branch a conditional term takes on the side being approached from
+func (l *limiter) branch(term Term) Term {
+	x := l.near()
+
+	pick := func(cond bool, a, b Term) Term {
+		if cond {
+			return a
+		}
+		return b
+	}
+
+	switch e := term.(type) {
+	case Greater:
+		return pick(e.A.E(x) > e.B.E(x), e.If, e.Else)
+	case Less:
+		return pick(e.A.E(x) < e.B.E(x), e.If, e.Else)
+	case GreaterEqual:
+		return pick(e.A.E(x) >= e.B.E(x), e.If, e.Else)
+	case LessEqual:
+		return pick(e.A.E(x) <= e.B.E(x), e.If, e.Else)
+	case Equal:
+		return pick(e.A.E(x) == e.B.E(x), e.If, e.Else)
+	case NotEqual:
+		return pick(e.A.E(x) != e.B.E(x), e.If, e.Else)
+	case Range:
+		v := e.X.E(x)
+		return pick(v >= e.A.E(x) && v <= e.B.E(x), e.If, e.Else)
+	}
+
+	return term
+}
+
+// numerical extrapolates the limit of a term from points approaching x0, returning the value and an error estimate.
+// It uses richardson extrapolation, assuming the error is a power series in the distance from x0 (or 1/x at infinity).
+func (l *limiter) numerical(term Term) (float64, float64) {
+	const steps = 12
+
+	at := func(h float64) float64 {
+		if math.IsInf(l.x0, 0) {
+			return term.E(-l.side / h)
+		}
+		return term.E(l.x0 + l.side*h*math.Max(1, math.Abs(l.x0)))
+	}
+
+	r := make([][]float64, steps)
+
+	best, bestErr := math.NaN(), math.Inf(1)
+	h := 1e-2
+	for k := 0; k < steps; k++ {
+		r[k] = make([]float64, k+1)
+		r[k][0] = at(h)
+
+		for j := 1; j <= k; j++ {
+			r[k][j] = r[k][j-1] + (r[k][j-1]-r[k-1][j-1])/(math.Pow(2, float64(j))-1)
+		}
+
+		if k > 0 {
+			err := math.Abs(r[k][k] - r[k-1][k-1])
+			if err < bestErr {
+				best, bestErr = r[k][k], err
+			}
+		}
+
+		h /= 2
+	}
+
+	return best, bestErr
+}
+
+// ratio simplifies n / d as a single product, so that common factors cancel
+func ratio(n, d Term) Term {
+	return collect(append(factors(n, 1), factors(d, -1)...))
+}
+
+// factors splits a term into the factors it is a product of, each raised to the power p
+func factors(term Term, p float64) Prod {
+	switch e := term.(type) {
+	case Div:
+		return append(factors(e.N, p), factors(e.D, -p)...)
+	case Mul:
+		return append(factors(e.A, p), factors(e.B, p)...)
+	case Prod:
+		out := make(Prod, 0, len(e))
+		for _, factor := range e {
+			out = append(out, factors(factor, p)...)
+		}
+		return out
+	case TP:
+		if e.P == math.Trunc(e.P) {
+			return factors(e.X, p*e.P)
+		}
+		return Prod{TP{e.X, p * e.P}}
+	case S:
+		return Prod{S(math.Pow(float64(e), p))}
+	}
+
+	return Prod{TP{term, p}}
+}
+
+// unary splits a single argument term into its argument, and a function that rebuilds it around a new argument
+func unary(term Term) (Term, func(Term) Term, bool) {
+	switch e := term.(type) {
+	case Exp:
+		return e.X, func(t Term) Term { return Exp{t} }, true
+	case Ln:
+		return e.X, func(t Term) Term { return Ln{t} }, true
+	case Log2:
+		return e.X, func(t Term) Term { return Log2{t} }, true
+	case Log10:
+		return e.X, func(t Term) Term { return Log10{t} }, true
+	case Sqrt:
+		return e.X, func(t Term) Term { return Sqrt{t} }, true
+	case Cbrt:
+		return e.X, func(t Term) Term { return Cbrt{t} }, true
+	case Sin:
+		return e.X, func(t Term) Term { return Sin{t} }, true
+	case Cos:
+		return e.X, func(t Term) Term { return Cos{t} }, true
+	case Tan:
+		return e.X, func(t Term) Term { return Tan{t} }, true
+	case Sec:
+		return e.X, func(t Term) Term { return Sec{t} }, true
+	case Csc:
+		return e.X, func(t Term) Term { return Csc{t} }, true
+	case Cot:
+		return e.X, func(t Term) Term { return Cot{t} }, true
+	case Sinh:
+		return e.X, func(t Term) Term { return Sinh{t} }, true
+	case Cosh:
+		return e.X, func(t Term) Term { return Cosh{t} }, true
+	case Tanh:
+		return e.X, func(t Term) Term { return Tanh{t} }, true
+	case Sech:
+		return e.X, func(t Term) Term { return Sech{t} }, true
+	case Csch:
+		return e.X, func(t Term) Term { return Csch{t} }, true
+	case Coth:
+		return e.X, func(t Term) Term { return Coth{t} }, true
+	case Abs:
+		return e.X, func(t Term) Term { return Abs{t} }, true
+	case Asin:
+		return e.X, func(t Term) Term { return Asin{t} }, true
+	case Acos:
+		return e.X, func(t Term) Term { return Acos{t} }, true
+	case Atan:
+		return e.X, func(t Term) Term { return Atan{t} }, true
+	case Asec:
+		return e.X, func(t Term) Term { return Asec{t} }, true
+	case Acsc:
+		return e.X, func(t Term) Term { return Acsc{t} }, true
+	case Acot:
+		return e.X, func(t Term) Term { return Acot{t} }, true
+	case Asinh:
+		return e.X, func(t Term) Term { return Asinh{t} }, true
+	case Acosh:
+		return e.X, func(t Term) Term { return Acosh{t} }, true
+	case Atanh:
+		return e.X, func(t Term) Term { return Atanh{t} }, true
+	case Asech:
+		return e.X, func(t Term) Term { return Asech{t} }, true
+	case Acsch:
+		return e.X, func(t Term) Term { return Acsch{t} }, true
+	case Acoth:
+		return e.X, func(t Term) Term { return Acoth{t} }, true
+	case Erf:
+		return e.X, func(t Term) Term { return Erf{t} }, true
+	case Erfc:
+		return e.X, func(t Term) Term { return Erfc{t} }, true
+	case Gamma:
+		return e.X, func(t Term) Term { return Gamma{t} }, true
+	case LogGamma:
+		return e.X, func(t Term) Term { return LogGamma{t} }, true
+	case Digamma:
+		return e.X, func(t Term) Term { return Digamma{t} }, true
+	case Polygamma:
+		return e.X, func(t Term) Term { return Polygamma{e.N, t} }, true
+	case BesselJ:
+		return e.X, func(t Term) Term { return BesselJ{e.N, t} }, true
+	case BesselY:
+		return e.X, func(t Term) Term { return BesselY{e.N, t} }, true
+	}
+
+	return nil, nil, false
+}
+
+// sign returns -1 for negative numbers and 1 otherwise
+func sign(v float64) int {
+	if v < 0 {
+		return -1
+	}
+
+	return 1
+}
@@ -0,0 +1,248 @@
+package alg
+
+import (
+	"math"
+)
+
+/*
+Logs defines logarithms to bases other than e:
+  Log   => The logarithm of X to the base Base
+  Log2  => The logarithm of a term to the base 2
+  Log10 => The logarithm of a term to the base 10
+*/
+
+func init() {
+	register(TidLog, Registration{
+		Name:  "log",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Log{Base: args[0], X: args[1]}, nil
+		},
+		Type: Log{},
+	})
+	register(TidLog2, Registration{
+		Name:  "log2",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Log2{X: args[0]}, nil
+		},
+		Type: Log2{},
+	})
+	register(TidLog10, Registration{
+		Name:  "log10",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Log10{X: args[0]}, nil
+		},
+		Type: Log10{},
+	})
+}
+
+type Log struct {
+	Base, X Term
+}
+
+func (e Log) Tokenise() Tokens {
+	t := Tokens{{id: TidLog}}
+	t = append(t, e.Base.Tokenise()...)
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Log) E(x float64) float64 {
+	return math.Log(e.X.E(x)) / math.Log(e.Base.E(x))
+}
+
+func (e Log) Dx() Term {
+	if ok, b := e.Base.Is(); ok {
+		return Div{
+			e.X.Dx(),
+			Prod{S(math.Log(b)), e.X},
+		}.T()
+	}
+
+	// log_b(x) = ln(x) / ln(b), when the base isn't constant
+	return Div{Ln{e.X}, Ln{e.Base}}.Dx()
+}
+
+func (e Log) T() Term {
+	e = simplified(e)
+
+	bok, bv := e.Base.Is()
+	xok, xv := e.X.Is()
+
+	if bok && xok {
+		return S(math.Log(xv) / math.Log(bv))
+	}
+
+	base, arg := e.Base, e.X
+	if _, ok := base.(EulerE); ok {
+		return Ln{arg}.T()
+	}
+
+	// log_b(b) = 1 and log_b(b^x) = x
+	if key(base) == key(arg) {
+		return S(1)
+	}
+
+	switch a := e.X.(type) {
+	case TPT:
+		if key(a.A) == key(base) {
+			return a.B
+		}
+	case TP:
+		if key(a.X) == key(base) {
+			return S(a.P)
+		}
+	case PT:
+		if bok && a.V == bv {
+			return a.X
+		}
+	}
+
+	return Log{base, arg}
+}
+
+func (e Log) Is() (bool, float64) {
+	bok, bv := e.Base.Is()
+	xok, xv := e.X.Is()
+
+	if !bok || !xok {
+		return false, 0
+	}
+
+	return true, math.Log(xv) / math.Log(bv)
+}
+
+func (e Log) Children() []Term {
+	return []Term{e.Base, e.X}
+}
+
+func (e Log) WithChildren(children []Term) Term {
+	e.Base, e.X = children[0], children[1]
+	return e
+}
+
+type Log2 struct {
+	X Term
+}
+
+func (e Log2) Tokenise() Tokens {
+	t := Tokens{{id: TidLog2}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Log2) E(x float64) float64 {
+	return math.Log2(e.X.E(x))
+}
+
+func (e Log2) Dx() Term {
+	return Div{
+		e.X.Dx(),
+		Prod{S(math.Ln2), e.X},
+	}.T()
+}
+
+func (e Log2) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Log2(val))
+	}
+
+	// log2(2^u) = u, whichever form the power simplified to
+	switch a := e.X.(type) {
+	case PT:
+		if a.V == 2 {
+			return a.X
+		}
+	case TPT:
+		if ok, v := a.A.Is(); ok && v == 2 {
+			return a.B
+		}
+	}
+
+	return Log2{e.X}
+}
+
+func (e Log2) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Log2(val)
+}
+
+func (e Log2) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Log2) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Log10 struct {
+	X Term
+}
+
+func (e Log10) Tokenise() Tokens {
+	t := Tokens{{id: TidLog10}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Log10) E(x float64) float64 {
+	return math.Log10(e.X.E(x))
+}
+
+func (e Log10) Dx() Term {
+	return Div{
+		e.X.Dx(),
+		Prod{S(math.Ln10), e.X},
+	}.T()
+}
+
+func (e Log10) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Log10(val))
+	}
+
+	// log10(10^u) = u, whichever form the power simplified to
+	switch a := e.X.(type) {
+	case PT:
+		if a.V == 10 {
+			return a.X
+		}
+	case TPT:
+		if ok, v := a.A.Is(); ok && v == 10 {
+			return a.B
+		}
+	}
+
+	return Log10{e.X}
+}
+
+func (e Log10) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Log10(val)
+}
+
+func (e Log10) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Log10) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
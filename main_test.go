@@ -1,7 +1,15 @@
 package alg
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math"
+	"math/cmplx"
+	"math/rand"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -32,11 +40,24 @@ func TestTokens_Parse(t *testing.T) {
 		"sin sin x":                     Sin{X: Sin{X: X{}}},
 		"+[ * 1.00 2.00 + 3.00 4.00 ]+": Sum{Mul{A: S(1), B: S(2)}, Add{A: S(3), B: S(4)}},
 		"^ sech x 2":                    TPT{A: Sech{X{}}, B: S(2)},
+		"atan2 asin x acosh 2":          Atan2{Y: Asin{X: X{}}, X: Acosh{X: S(2)}},
+		"mod abs x max floor x 1":       Mod{A: Abs{X: X{}}, B: Max{A: Floor{X: X{}}, B: S(1)}},
+		"beta erf x polygamma 2 x":      Beta{A: Erf{X: X{}}, B: Polygamma{N: 2, X: X{}}},
+		"sin * 2 pi":                    Sin{X: Mul{A: S(2), B: Pi{}}},
+		"^ euler + x phi":               TPT{A: EulerE{}, B: Add{A: X{}, B: Phi{}}},
+		"log 3 sqrt cbrt log10 log2 x":  Log{Base: S(3), X: Sqrt{X: Cbrt{X: Log10{X: Log2{X: X{}}}}}},
+		"*[ x 2 sin x ]*":               Prod{X{}, S(2), Sin{X: X{}}},
 	}
 
 	for s, term := range testCases {
-		ts := Tokenise(s)
-		tree := ts.Parse()
+		ts, err := Tokenise(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := ts.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		trs := tree.Tokenise()
 
@@ -47,6 +68,528 @@ func TestTokens_Parse(t *testing.T) {
 	}
 }
 
+// cube is a term from outside the package, registered to check that it can be parsed and gob encoded
+type cube struct {
+	X Term
+}
+
+var tidCube = Register(Registration{
+	Name:  "cube",
+	Arity: 1,
+	New: func(_ float64, args []Term) (Term, error) {
+		return cube{args[0]}, nil
+	},
+	Type: cube{},
+})
+
+func (e cube) E(x float64) float64 {
+	return math.Pow(e.X.E(x), 3)
+}
+
+func (e cube) Dx() Term {
+	return Prod{S(3), e.X.Dx(), TP{e.X, 2}}.T()
+}
+
+func (e cube) T() Term {
+	return cube{e.X.T()}
+}
+
+func (e cube) Is() (bool, float64) {
+	return false, 0
+}
+
+func (e cube) Tokenise() Tokens {
+	return append(Tokens{NewToken(tidCube, 0)}, e.X.Tokenise()...)
+}
+
+// scale is a registered term that carries a value in its token, like TP
+type scale struct {
+	K float64
+	X Term
+}
+
+var tidScale = Register(Registration{
+	Name:   "scale",
+	Arity:  1,
+	Valued: true,
+	New: func(val float64, args []Term) (Term, error) {
+		return scale{val, args[0]}, nil
+	},
+	Type: scale{},
+})
+
+func (e scale) E(x float64) float64 {
+	return e.K * e.X.E(x)
+}
+
+func (e scale) Dx() Term {
+	return scale{e.K, e.X.Dx()}
+}
+
+func (e scale) T() Term {
+	return scale{e.K, e.X.T()}
+}
+
+func (e scale) Is() (bool, float64) {
+	return false, 0
+}
+
+func (e scale) Tokenise() Tokens {
+	return append(Tokens{NewToken(tidScale, e.K)}, e.X.Tokenise()...)
+}
+
+func TestRegister(t *testing.T) {
+	ts, err := Tokenise("cube + x 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := ts.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := cube{Add{A: X{}, B: S(1)}}
+	if !reflect.DeepEqual(tree, want) {
+		t.Logf("Register failed to parse: (%s)\nWanted: %v\nGot:    %v\n", ts.String(), want, tree)
+		t.Fail()
+	}
+
+	if s := tree.Tokenise(); s.String() != "cube + x 1.00 " {
+		t.Logf("Register failed to print: %s\n", s.String())
+		t.Fail()
+	}
+
+	var buf bytes.Buffer
+	var in, out Term = Sum{tree, Sin{Pi{}}}, nil
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Logf("Register failed to gob encode:\nWanted: %v\nGot:    %v\n", in, out)
+		t.Fail()
+	}
+
+	if _, err := Tokenise("cubed x"); err == nil {
+		t.Log("Tokenise accepted an unregistered token name")
+		t.Fail()
+	}
+
+	// Values of registered tokens have to survive printing and tell terms apart
+	a, b := scale{0.1, X{}}, scale{2, X{}}
+
+	if Same(a, b) || Hash(a) == Hash(b) {
+		t.Log("Register failed to key on the value of a token")
+		t.Fail()
+	}
+
+	ts = a.Tokenise()
+	if ts.String() != "scale:0.1 x " {
+		t.Logf("Register failed to print a value: %s\n", ts.String())
+		t.Fail()
+	}
+
+	ts, err = Tokenise(ts.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree, err := ts.Parse(); err != nil || !reflect.DeepEqual(tree, a) {
+		t.Logf("Register failed to read a value back\nWanted: %v\nGot:    %v (%v)\n", a, tree, err)
+		t.Fail()
+	}
+
+	if _, err := Tokenise("cube:2 x"); err == nil {
+		t.Log("Tokenise accepted a value for a token without one")
+		t.Fail()
+	}
+}
+
+var (
+	softplus = Define("softplus", Ln{Add{S(1), Exp{X{}}}})
+	square   = DefineInline("square", TP{X{}, 2})
+)
+
+func TestDefine(t *testing.T) {
+	call := softplus.Of(Sx{2})
+
+	if e, dx := call.E(0), call.Dx().E(0); math.Abs(e-math.Ln2) > 1e-15 || math.Abs(dx-1) > 1e-15 {
+		t.Logf("Define failed to evaluate softplus(2x) at 0\nWanted: %v, %v\nGot:    %v, %v\n", math.Ln2, 1.0, e, dx)
+		t.Fail()
+	}
+
+	ts, err := Tokenise("softplus square x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := ts.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Call{"softplus", Call{"square", X{}}}
+	if !reflect.DeepEqual(tree, want) {
+		t.Logf("Define failed to parse: (%s)\nWanted: %v\nGot:    %v\n", ts.String(), want, tree)
+		t.Fail()
+	}
+
+	// Only calls to square are inlined
+	got := tree.T()
+	if want := (Call{"softplus", TP{X{}, 2}}); !reflect.DeepEqual(got, want) {
+		gs, ws := got.Tokenise(), want.Tokenise()
+		t.Logf("Define failed to inline:\nWanted: %s\nGot:    %s\n", ws.String(), gs.String())
+		t.Fail()
+	}
+
+	if s := square.Of(Sin{X{}}).Tokenise(); s.String() != "square sin x " {
+		t.Logf("Define failed to print: %s\n", s.String())
+		t.Fail()
+	}
+
+	// Calls to undefined functions are keyed by name, so they are neither other terms nor each other
+	undefined := Call{"undefined", X{}}
+	for _, other := range []Term{Call{"sin", X{}}, Sin{X{}}, softplus.Of(X{})} {
+		if Same(undefined, other) || Hash(undefined) == Hash(other) {
+			ts := other.Tokenise()
+			t.Logf("Define failed to tell an undefined call from (%s)\n", ts.String())
+			t.Fail()
+		}
+	}
+
+	if _, err := EvalChecked(undefined, 1); !errors.Is(err, ErrUndefined) {
+		t.Logf("EvalChecked failed to report an undefined function\nWanted: %v\nGot:    %v\n", ErrUndefined, err)
+		t.Fail()
+	}
+
+	ts = undefined.Tokenise()
+	if _, err := ts.Parse(); err == nil {
+		t.Log("Define failed to reject parsing an undefined call")
+		t.Fail()
+	}
+
+	ts = softplus.Of(X{}).Tokenise()
+	if tree, err := ts.Parse(); err != nil || !reflect.DeepEqual(tree, softplus.Of(X{})) {
+		t.Logf("Define failed to parse the tokens of a call\nGot:    %v (%v)\n", tree, err)
+		t.Fail()
+	}
+}
+
+func TestTraverse(t *testing.T) {
+	term := Add{Sin{X{}}, Prod{S(2), Polygamma{1, X{}}}}
+
+	isX := func(t Term) bool {
+		_, ok := t.(X)
+		return ok
+	}
+
+	if n := Count(term, func(Term) bool { return true }); n != 7 {
+		t.Logf("Count failed\nWanted: 7\nGot:    %d\n", n)
+		t.Fail()
+	}
+
+	if n := Count(term, isX); n != 2 {
+		t.Logf("Count failed to count x\nWanted: 2\nGot:    %d\n", n)
+		t.Fail()
+	}
+
+	depth := Fold(term, func(_ Term, children []int) int {
+		d := 0
+		for _, c := range children {
+			if c > d {
+				d = c
+			}
+		}
+		return d + 1
+	})
+	if depth != 4 {
+		t.Logf("Fold failed to find the depth\nWanted: 4\nGot:    %d\n", depth)
+		t.Fail()
+	}
+
+	if found, ok := Find(term, func(t Term) bool { return len(Children(t)) == 1 }); !ok || !reflect.DeepEqual(found, Sin{X{}}) {
+		t.Logf("Find failed\nWanted: %v\nGot:    %v\n", Sin{X{}}, found)
+		t.Fail()
+	}
+
+	mapped := Map(term, func(t Term) Term {
+		if isX(t) {
+			return Sx{3}
+		}
+		return t
+	})
+	if want := (Add{Sin{Sx{3}}, Prod{S(2), Polygamma{1, Sx{3}}}}); !reflect.DeepEqual(mapped, want) {
+		t.Logf("Map failed\nWanted: %v\nGot:    %v\n", want, mapped)
+		t.Fail()
+	}
+
+	visited := 0
+	Walk(term, func(t Term) bool {
+		visited++
+		_, ok := t.(Prod)
+		return !ok
+	})
+	if visited != 4 {
+		t.Logf("Walk failed to skip children\nWanted: 4\nGot:    %d\n", visited)
+		t.Fail()
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	testCases := []struct {
+		got, want Term
+	}{
+		{Substitute(Add{Sx{2}, Sin{X{}}}, Add{X{}, S(1)}), Add{Mul{S(2), Add{X{}, S(1)}}, Sin{Add{X{}, S(1)}}}},
+		{Compose(Exp{X{}}, Polygamma{2, Sx{3}}), Exp{Polygamma{2, Sx{3}}}},
+		{Compose(Polygamma{2, Sx{3}}, Exp{X{}}), Polygamma{2, Mul{S(3), Exp{X{}}}}},
+		{SubstituteSubtree(Add{Sin{X{}}, Cos{Sin{X{}}}}, Sin{X{}}, S(0)), Add{S(0), Cos{S(0)}}},
+		{SubstituteSubtree(Sin{Sin{X{}}}, Sin{X{}}, Sin{Sin{X{}}}), Sin{Sin{Sin{X{}}}}},
+	}
+
+	for _, c := range testCases {
+		if !reflect.DeepEqual(c.got, c.want) {
+			gs, ws := c.got.Tokenise(), c.want.Tokenise()
+			t.Logf("Substitute failed\nWanted: %s\nGot:    %s\n", ws.String(), gs.String())
+			t.Fail()
+		}
+	}
+}
+
+func TestDAG(t *testing.T) {
+	term := Div{Sin{Sx{2}}, Add{X{}, Sin{Sx{2}}}}.Dx()
+	d := NewDAG(term)
+
+	if n, all := len(d.Nodes), Count(term, func(Term) bool { return true }); n >= all {
+		t.Logf("DAG failed to share any nodes: %d of %d\n", n, all)
+		t.Fail()
+	}
+
+	// A DAG built from its nodes alone should evaluate the same
+	built := &DAG{Nodes: d.Nodes}
+
+	for _, x := range []float64{-2, 0.5, 3} {
+		if e, b, want := d.E(x), built.E(x), term.E(x); math.Abs(e-want) > 1e-12 || e != b {
+			t.Logf("DAG failed to evaluate at %v\nWanted: %v\nGot:    %v, %v\n", x, want, e, b)
+			t.Fail()
+		}
+	}
+
+	// Concurrent evaluations each get their own values
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		x := float64(i) / 4
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if e, want := d.E(x), term.E(x); math.Abs(e-want) > 1e-12 {
+					t.Errorf("DAG failed to evaluate concurrently at %v\nWanted: %v\nGot:    %v\n", x, want, e)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	shared := CSE(Add{Mul{Sin{X{}}, Sin{X{}}}, Sin{X{}}})
+	if want := []Shared{{Sin{X{}}, 3, 2}}; !reflect.DeepEqual(shared, want) {
+		t.Logf("CSE failed\nWanted: %v\nGot:    %v\n", want, shared)
+		t.Fail()
+	}
+}
+
+func BenchmarkDAG(b *testing.B) {
+	// Derivatives repeat their subtrees, which the tree evaluates every time and the DAG only once
+	term := DxN(Div{Sin{Sx{2}}, Add{X{}, Exp{Sin{Sx{2}}}}}, 3)
+
+	b.Run("Tree", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			term.E(0.5)
+		}
+	})
+
+	b.Run("DAG", func(b *testing.B) {
+		d := NewDAG(term)
+		b.ReportMetric(float64(len(d.Nodes)), "nodes")
+		b.ReportMetric(float64(Count(term, func(Term) bool { return true })), "terms")
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			d.E(0.5)
+		}
+	})
+}
+
+func TestHash(t *testing.T) {
+	equal := [][2]Term{
+		{TP{X{}, 2}, TPT{X{}, S(2)}},
+		{Sin{Add{X{}, S(1)}}, Sin{Add{X{}, S(1)}}},
+	}
+
+	for _, c := range equal {
+		if !Same(c[0], c[1]) || Hash(c[0]) != Hash(c[1]) {
+			a, b := c[0].Tokenise(), c[1].Tokenise()
+			t.Logf("Hash failed to match equal terms: (%s) and (%s)\n", a.String(), b.String())
+			t.Fail()
+		}
+	}
+
+	if Same(Add{X{}, S(0)}, X{}) || Hash(Sin{X{}}) == Hash(Cos{X{}}) {
+		t.Logf("Hash failed to separate different terms\n")
+		t.Fail()
+	}
+
+	in := NewInterner()
+	a := in.Intern(Add{Sin{X{}}, Mul{Sin{X{}}, S(2)}})
+	b := in.Intern(Sin{X{}})
+
+	if c := in.Intern(Add{Sin{X{}}, Mul{Sin{X{}}, S(2)}}); c != a {
+		t.Logf("Interner failed to return the same pointer for equal terms\n")
+		t.Fail()
+	}
+
+	// Sin{X{}}, X{}, S(2), the Mul and the Add
+	if n := in.Len(); n != 5 || !Same(*a, Add{Sin{X{}}, Mul{Sin{X{}}, S(2)}}) || !Same(*b, Sin{X{}}) {
+		t.Logf("Interner failed\nWanted: 5 terms\nGot:    %d terms\n", n)
+		t.Fail()
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := Stats(Add{Sin{Sx{2}}, Prod{X{}, X{}, S(3)}})
+
+	want := TermStats{
+		Nodes: 7,
+		Depth: 3,
+		Ops:   map[string]int{"Add": 1, "Sin": 1, "Sx": 1, "Prod": 1, "X": 2, "S": 1},
+		Cost:  24,
+	}
+
+	if !reflect.DeepEqual(s, want) {
+		t.Logf("Stats failed\nWanted: %v\nGot:    %v\n", want, s)
+		t.Fail()
+	}
+
+	messy := Prod{S(1), Add{X{}, S(0)}, TPT{X{}, S(2)}}
+	if c := Cheaper(messy, messy.T()); Stats(c).Cost >= Stats(messy).Cost {
+		t.Logf("Cheaper failed to prefer the simplified term\nGot: %v\n", Stats(c))
+		t.Fail()
+	}
+}
+
+func TestVisualise(t *testing.T) {
+	term := Add{Mul{Sin{X{}}, Sin{X{}}}, TP{Sx{2}, 3}}
+
+	want := "Add\n" +
+		"├── Mul\n" +
+		"│   ├── Sin\n" +
+		"│   │   └── X\n" +
+		"│   └── Sin\n" +
+		"│       └── X\n" +
+		"└── TP P=3\n" +
+		"    └── Sx S=2\n"
+
+	if tree := Tree(term); tree != want {
+		t.Logf("Tree failed\nWanted:\n%s\nGot:\n%s\n", want, tree)
+		t.Fail()
+	}
+
+	dot := DOT(term)
+	if !strings.Contains(dot, `[label="Sin\n2 uses", style=filled`) || strings.Count(dot, "->") != 6 {
+		t.Logf("DOT failed to share Sin{X{}}\nGot:\n%s\n", dot)
+		t.Fail()
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := Add{Mul{S(2), Sin{X{}}}, TP{X{}, 2}}
+	b := Add{Mul{S(3), Sin{X{}}}, TP{Cos{X{}}, 2}}
+
+	d := Diff(a, b)
+
+	want := "at [0 0]: 2.00 => 3.00\n" +
+		"at [1 0]: x => cos x"
+
+	if d.String() != want || !Same(d.Apply(a), b) {
+		t.Logf("Diff failed\nWanted:\n%s\nGot:\n%s\n", want, d.String())
+		t.Fail()
+	}
+
+	if d := Diff(TP{X{}, 2}, TPT{X{}, S(2)}); len(d) != 0 {
+		t.Logf("Diff failed on equal terms\nGot:\n%s\n", d.String())
+		t.Fail()
+	}
+
+	if d := Diff(a, Sin{X{}}); len(d) != 1 || len(d[0].Path) != 0 {
+		t.Logf("Diff failed to replace the root\nGot:\n%s\n", d.String())
+		t.Fail()
+	}
+}
+
+func TestMatch(t *testing.T) {
+	wave, err := Tokenise("* #a sin + * #b x #c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pattern, err := wave.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		pattern, term Term
+		want          Bindings
+	}{
+		{pattern, Prod{Sin{Add{S(1), Sx{2}}}, S(3)}, Bindings{"#a": S(3), "#b": S(2), "#c": S(1)}},
+		{pattern, Mul{Pi{}, Sin{Sum{Mul{X{}, S(4)}, S(-1)}}}, Bindings{"#a": Pi{}, "#b": S(4), "#c": S(-1)}},
+		{pattern, Mul{X{}, Sin{Add{Sx{2}, S(1)}}}, nil},
+		{pattern, Sin{Add{Sx{2}, S(1)}}, nil},
+		{Add{Wild{"_a"}, Sin{Wild{"_b"}}}, Sum{X{}, Sin{X{}}, S(2)}, Bindings{"_a": Sum{X{}, S(2)}, "_b": X{}}},
+		{Mul{Wild{"#c"}, Wild{"$v"}}, Mul{Exp{X{}}, S(2)}, Bindings{"#c": S(2), "$v": Exp{X{}}}},
+		{Add{Wild{"_a"}, Wild{"_a"}}, Add{Cos{X{}}, Cos{X{}}}, Bindings{"_a": Cos{X{}}}},
+		{Add{Wild{"_a"}, Wild{"_a"}}, Add{Cos{X{}}, S(1)}, nil},
+		{TPT{Wild{"$u"}, Wild{"#n"}}, TP{Sin{X{}}, 2}, Bindings{"$u": Sin{X{}}, "#n": S(2)}},
+		{Sub{Wild{"_a"}, X{}}, Sub{X{}, S(1)}, nil},
+	}
+
+	for _, c := range testCases {
+		b, ok := Match(c.pattern, c.term)
+		if ok != (c.want != nil) || (ok && !reflect.DeepEqual(b, c.want)) {
+			ts := c.term.Tokenise()
+			t.Logf("Match failed on case: (%s)\nWanted: %v\nGot:    %v\n", ts.String(), c.want, b)
+			t.Fail()
+		}
+	}
+
+	pt := pattern.Tokenise()
+	if printed := pt.String(); printed != "* #a sin + * #b x #c " {
+		t.Logf("Wildcards failed to print\nWanted: %s\nGot:    %s\n", "* #a sin + * #b x #c ", printed)
+		t.Fail()
+	}
+
+	// Wildcards are keyed by their names alone, whatever order they were first seen in
+	if Same(Wild{"_p"}, Wild{"_q"}) || !Same(pattern, Mul{Wild{"#a"}, Sin{Add{Mul{Wild{"#b"}, X{}}, Wild{"#c"}}}}) {
+		t.Logf("Wildcards failed to key by name\n")
+		t.Fail()
+	}
+
+	b, _ := Match(pattern, Mul{S(3), Sin{Add{Sx{2}, S(1)}}})
+	if got := b.Apply(Mul{Wild{"#a"}, Cos{Wild{"#c"}}}); !Same(got, Mul{S(3), Cos{S(1)}}) {
+		ts := got.Tokenise()
+		t.Logf("Bindings failed to apply\nGot: %s\n", ts.String())
+		t.Fail()
+	}
+}
+
 func TestTidy(t *testing.T) {
 	messy := []Term{
 		Prod{S(0), Add{S(0), Exp{Sx{-1}}}},
@@ -55,6 +598,28 @@ func TestTidy(t *testing.T) {
 		Prod{S(1), S(2), S(3), X{}, X{}},
 		Div{Add{Prod{S(0), X{}}, X{}}, Sin{X{}}},
 		Prod{X{}, X{}},
+		Sin{Asin{X{}}},
+		Atanh{Tanh{Sx{2}}},
+		Gamma{S(5)},
+		Polygamma{0, Add{S(0), X{}}},
+		Sin{Prod{S(3), Pi{}}},
+		Cos{Mul{Pi{}, S(3)}},
+		Sin{Div{Mul{S(3), Pi{}}, S(2)}},
+		Ln{EulerE{}},
+		Ln{TPT{EulerE{}, S(3)}},
+		TPT{EulerE{}, X{}},
+		Log{S(2), TPT{S(2), Sx{3}}},
+		Log{X{}, TP{X{}, 3}},
+		Log{EulerE{}, X{}},
+		Sqrt{TP{X{}, 2}},
+		Cbrt{TP{Sx{2}, 3}},
+		Log2{TPT{S(2), X{}}},
+		Log10{PT{10, Sin{X{}}}},
+		Prod{S(2), Sin{Pi{}}, X{}},
+		Add{Sin{Pi{}}, S(1)},
+		Mul{Ln{EulerE{}}, X{}},
+		Add{Cos{Pi{}}, S(1)},
+		Exp{Sin{Mul{S(2), Pi{}}}},
 	}
 
 	tidy := []Term{
@@ -64,6 +629,28 @@ func TestTidy(t *testing.T) {
 		Prod{X{}, X{}, S(6)},
 		Div{X{}, Sin{X{}}},
 		Mul{X{}, X{}},
+		X{},
+		Sx{2},
+		S(24),
+		Digamma{X{}},
+		S(0),
+		S(-1),
+		S(-1),
+		S(1),
+		S(3),
+		Exp{X{}},
+		Sx{3},
+		S(3),
+		Ln{X{}},
+		Abs{X{}},
+		Sx{2},
+		X{},
+		Sin{X{}},
+		S(0),
+		S(1),
+		X{},
+		S(0),
+		S(1),
 	}
 
 	for i := 0; i < len(messy); i++ {
@@ -77,6 +664,13 @@ func TestTidy(t *testing.T) {
 			t.Logf("Test failed on case: (%s)\nWanted: %s\nGot     %s\n", ms.String(), ts.String(), ns.String())
 			t.Fail()
 		}
+
+		// Simplifying again shouldn't find anything more to do
+		if again := tidied.T(); !reflect.DeepEqual(again, tidied) {
+			as := again.Tokenise()
+			t.Logf("Test failed to reach a fixpoint on case: (%s)\nFirst:  %s\nSecond: %s\n", ms.String(), ns.String(), as.String())
+			t.Fail()
+		}
 	}
 }
 
@@ -99,3 +693,558 @@ func TestSigmoid(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestInverse(t *testing.T) {
+	testCases := []struct {
+		term Term
+		x    float64
+		e    float64
+		dx   float64
+	}{
+		{Asin{X{}}, 0.5, math.Pi / 6, 1 / math.Sqrt(0.75)},
+		{Acos{X{}}, 0.5, math.Pi / 3, -1 / math.Sqrt(0.75)},
+		{Atan{Sx{2}}, 0.5, math.Pi / 4, 1},
+		{Asec{X{}}, -2, 2 * math.Pi / 3, 1 / (2 * math.Sqrt(3))},
+		{Acot{X{}}, -1, 3 * math.Pi / 4, -0.5},
+		{Atan2{S(1), X{}}, -1, 3 * math.Pi / 4, -0.5},
+		{Asinh{X{}}, 0, 0, 1},
+		{Acosh{X{}}, 2, math.Log(2 + math.Sqrt(3)), 1 / math.Sqrt(3)},
+		{Atanh{X{}}, 0.5, math.Log(3) / 2, 4.0 / 3},
+		{Acoth{X{}}, 2, math.Log(3) / 2, -1.0 / 3},
+	}
+
+	for _, c := range testCases {
+		e, dx := c.term.E(c.x), c.term.Dx().E(c.x)
+
+		if math.Abs(e-c.e) > 1e-12 || math.Abs(dx-c.dx) > 1e-12 {
+			ts := c.term.Tokenise()
+			t.Logf("Inverse failed on case: (%s) at %v\nWanted: %v, %v\nGot:    %v, %v\n", ts.String(), c.x, c.e, c.dx, e, dx)
+			t.Fail()
+		}
+	}
+}
+
+func TestNonSmooth(t *testing.T) {
+	testCases := []struct {
+		term Term
+		x    float64
+		e    float64
+		dx   float64
+	}{
+		{Abs{Sx{-2}}, 1, 2, 2},
+		{Sign{Sub{X{}, S(3)}}, 1, -1, 0},
+		{Min{X{}, TP{X{}, 2}}, 2, 2, 1},
+		{Max{X{}, TP{X{}, 2}}, 2, 4, 4},
+		{Floor{Sx{-1}}, 0.5, -1, 0},
+		{Ceil{Sx{-1}}, 0.5, 0, 0},
+		{Mod{X{}, S(2)}, -1, 1, 1},
+		{Mod{X{}, S(-2)}, 1, -1, 1},
+	}
+
+	for _, c := range testCases {
+		e, dx := c.term.E(c.x), c.term.Dx().E(c.x)
+
+		if e != c.e || dx != c.dx {
+			ts := c.term.Tokenise()
+			t.Logf("NonSmooth failed on case: (%s) at %v\nWanted: %v, %v\nGot:    %v, %v\n", ts.String(), c.x, c.e, c.dx, e, dx)
+			t.Fail()
+		}
+	}
+}
+
+func TestSimplifyIn(t *testing.T) {
+	testCases := []struct {
+		term Term
+		x    Interval
+		want Term
+	}{
+		{Abs{X{}}, Interval{1, 2}, X{}},
+		{Abs{X{}}, Interval{-2, -1}, Mul{X{}, S(-1)}},
+		{Abs{X{}}, Interval{-1, 1}, Abs{X{}}},
+		{Add{Sign{X{}}, Max{X{}, S(3)}}, Interval{0.5, 2}, S(4)},
+		{Floor{X{}}, Interval{1, 1.5}, S(1)},
+		{Mod{X{}, S(2)}, Interval{4.5, 5}, Sub{X{}, S(4)}},
+		{Greater{X{}, S(0), Ln{X{}}, S(0)}, Interval{1, 2}, Ln{X{}}},
+	}
+
+	for _, c := range testCases {
+		got := SimplifyIn(c.term, c.x)
+
+		if !reflect.DeepEqual(got, c.want) {
+			ts, gs, ws := c.term.Tokenise(), got.Tokenise(), c.want.Tokenise()
+			t.Logf("SimplifyIn failed on case: (%s) over %v\nWanted: %s\nGot:    %s\n", ts.String(), c.x, ws.String(), gs.String())
+			t.Fail()
+		}
+	}
+}
+
+func TestSpecial(t *testing.T) {
+	const euler = 0.5772156649015329
+
+	testCases := []struct {
+		term Term
+		x    float64
+		e    float64
+		dx   float64
+	}{
+		{Erf{X{}}, 0, 0, 2 / math.SqrtPi},
+		{Erfc{Sx{2}}, 0, 1, -4 / math.SqrtPi},
+		{Gamma{X{}}, 1, 1, -euler},
+		{LogGamma{X{}}, 0.5, math.Log(math.SqrtPi), -euler - 2*math.Ln2},
+		{Digamma{X{}}, 1, -euler, math.Pi * math.Pi / 6},
+		{Polygamma{1, X{}}, 0.5, math.Pi * math.Pi / 2, -16.828796644234318},
+		{Beta{X{}, S(2)}, 1, 0.5, -0.75},
+		{BesselJ{0, X{}}, 1, 0.7651976865579666, -0.44005058574493355},
+		{BesselY{1, X{}}, 1, -0.7812128213002887, 0.8694697855159659},
+	}
+
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-12*math.Max(1, math.Abs(b))
+	}
+
+	for _, c := range testCases {
+		e, dx := c.term.E(c.x), c.term.Dx().E(c.x)
+
+		if !near(e, c.e) || !near(dx, c.dx) {
+			ts := c.term.Tokenise()
+			t.Logf("Special failed on case: (%s) at %v\nWanted: %v, %v\nGot:    %v, %v\n", ts.String(), c.x, c.e, c.dx, e, dx)
+			t.Fail()
+		}
+	}
+
+	// Known values simplify to exact terms, keeping pi symbolic
+	simplifyCases := []struct {
+		term, want Term
+	}{
+		{Erf{S(0)}, S(0)},
+		{Erfc{S(0)}, S(1)},
+		{Gamma{S(5)}, S(24)},
+		{Gamma{S(0.5)}, Sqrt{Pi{}}},
+		{Gamma{Add{S(2), S(0.5)}}, Mul{S(0.75), Sqrt{Pi{}}}},
+		{LogGamma{S(1)}, S(0)},
+		{LogGamma{S(0.5)}, Ln{Sqrt{Pi{}}}},
+		{Beta{S(0.5), S(0.5)}, Pi{}},
+		{Beta{S(1.5), S(0.5)}, Mul{S(0.5), Pi{}}},
+		{Beta{Sin{X{}}, S(1)}, Div{S(1), Sin{X{}}}},
+		{BesselJ{0, S(0)}, S(1)},
+		{BesselJ{3, S(0)}, S(0)},
+		{BesselJ{-3, X{}}, Mul{S(-1), BesselJ{3, X{}}}},
+		{BesselY{-2, X{}}, BesselY{2, X{}}},
+	}
+
+	for _, c := range simplifyCases {
+		if got := c.term.T(); !Same(got, c.want) {
+			ts, gs, ws := c.term.Tokenise(), got.Tokenise(), c.want.Tokenise()
+			t.Logf("Special failed to simplify (%s)\nWanted: %s\nGot:    %s\n", ts.String(), ws.String(), gs.String())
+			t.Fail()
+		}
+	}
+}
+
+func TestLogsAndRoots(t *testing.T) {
+	testCases := []struct {
+		term Term
+		x    float64
+		e    float64
+		dx   float64
+	}{
+		{Log{S(2), X{}}, 8, 3, 1 / (8 * math.Ln2)},
+		{Log{X{}, S(8)}, 2, 3, -3 / (2 * math.Ln2)},
+		{Log2{Sx{2}}, 4, 3, 1 / (4 * math.Ln2)},
+		{Log10{X{}}, 100, 2, 1 / (100 * math.Ln10)},
+		{Sqrt{X{}}, 4, 2, 0.25},
+		{Cbrt{X{}}, -8, -2, 1.0 / 12},
+	}
+
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-12*math.Max(1, math.Abs(b))
+	}
+
+	for _, c := range testCases {
+		e, dx := c.term.E(c.x), c.term.Dx().E(c.x)
+
+		if !near(e, c.e) || !near(dx, c.dx) {
+			ts := c.term.Tokenise()
+			t.Logf("LogsAndRoots failed on case: (%s) at %v\nWanted: %v, %v\nGot:    %v, %v\n", ts.String(), c.x, c.e, c.dx, e, dx)
+			t.Fail()
+		}
+	}
+}
+
+func TestApproximants(t *testing.T) {
+	cheb, err := Chebyshev(Exp{X{}}, 0, 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cheb.MaxErr > 1e-8 {
+		t.Logf("Chebyshev fit of e^x is too inaccurate: %g at %g\n", cheb.MaxErr, cheb.ArgMax)
+		t.Fail()
+	}
+
+	pade, err := Pade(Exp{X{}}, -1, 1, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pade.MaxErr > 1e-4 {
+		t.Logf("Pade fit of e^x is too inaccurate: %g at %g\n", pade.MaxErr, pade.ArgMax)
+		t.Fail()
+	}
+
+	if _, ok := pade.Term.(Div); !ok {
+		t.Logf("Pade fit should be a fraction, got %v\n", pade.Term)
+		t.Fail()
+	}
+}
+
+func TestDxN(t *testing.T) {
+	// sin(x)e^x has a fourth derivative of -4sin(x)e^x
+	term := Mul{Sin{X{}}, Exp{X{}}}
+	d4 := DxN(term, 4)
+
+	for _, x := range []float64{-1, 0.5, 2} {
+		want := -4 * term.E(x)
+		got := d4.E(x)
+
+		if math.Abs(want-got) > 1e-9 {
+			t.Logf("Fourth derivative failed at %v\nWanted: %v\nGot:    %v\n", x, want, got)
+			t.Fail()
+		}
+	}
+
+	// High orders should stay small enough to work with, rather than growing exponentially.
+	// The derivatives of e^sin(x) at 0 are 1, 1, 1, 0, -3, -8, -3, 56, 217, 64, -2951.
+	c := NewDerivativeCache()
+	d10 := c.DxN(Exp{Sin{X{}}}, 10)
+
+	if got := d10.E(0); math.Abs(got+2951) > 1e-9 {
+		t.Logf("Tenth derivative failed at 0\nWanted: %v\nGot:    %v\n", -2951, got)
+		t.Fail()
+	}
+
+	if n := Stats(d10).Nodes; n > 1000 {
+		t.Logf("Tenth derivative has %d nodes, more than 1000\n", n)
+		t.Fail()
+	}
+
+	// Taking the derivatives again only uses the cache
+	n := c.Len()
+	if again := c.DxN(Exp{Sin{X{}}}, 10); !Same(again, d10) || c.Len() != n {
+		t.Logf("DerivativeCache failed to reuse its entries: %d before, %d after\n", n, c.Len())
+		t.Fail()
+	}
+
+	// cos(x) is a factor of the first derivative, so its own derivative is already cached
+	if c.Dx(Cos{X{}}); c.Len() != n {
+		t.Log("DerivativeCache failed to reuse the derivative of a subterm")
+		t.Fail()
+	}
+}
+
+func TestLimit(t *testing.T) {
+	testCases := []struct {
+		term Term
+		x0   float64
+		want float64
+	}{
+		{Div{Sin{X{}}, X{}}, 0, 1},
+		{Div{Sub{S(1), Cos{X{}}}, TP{X{}, 2}}, 0, 0.5},
+		{Div{Add{X{}, S(1)}, Sx{2}}, math.Inf(1), 0.5},
+		{Sub{TP{X{}, 2}, Sx{100}}, math.Inf(1), math.Inf(1)},
+		{TPT{Add{S(1), Div{S(1), X{}}}, X{}}, math.Inf(1), math.E},
+		{Max{Div{Sin{X{}}, X{}}, S(0.5)}, 0, 1},
+		{Abs{Div{Sub{TP{X{}, 2}, S(1)}, Sub{X{}, S(1)}}}, 1, 2},
+		{Mod{Exp{TP{X{}, 2}}, S(1)}, 0, 0},
+	}
+
+	for _, c := range testCases {
+		res, err := Limit(c.term, c.x0, FromBoth)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Numerical || math.Abs(res.Value-c.want) > 1e-9 && res.Value != c.want {
+			ts := c.term.Tokenise()
+			t.Logf("Limit failed on case: (%s) at %v\nWanted: %v\nGot:    %v\n", ts.String(), c.x0, c.want, res)
+			t.Fail()
+		}
+	}
+
+	if _, err := Limit(Div{S(1), X{}}, 0, FromBoth); err == nil {
+		t.Log("Limit of 1/x at 0 should not exist")
+		t.Fail()
+	}
+
+	// Terms the symbolic rules don't know about fall back to extrapolation, which is slow with logs and loses absolute precision for large values
+	numerical := []struct {
+		term Term
+		x0   float64
+		dir  Direction
+		want float64
+	}{
+		{scale{1, Add{S(1), Mul{X{}, Ln{X{}}}}}, 0, FromAbove, 1},
+		{scale{1, Mul{X{}, Sin{Div{S(1), X{}}}}}, 0, FromAbove, 0},
+		{scale{1e9, Div{Add{X{}, Sqrt{X{}}}, X{}}}, math.Inf(1), FromBoth, 1e9},
+		{scale{1e12, Div{Sin{X{}}, X{}}}, 0, FromBoth, 1e12},
+	}
+
+	for _, c := range numerical {
+		res, err := Limit(c.term, c.x0, c.dir)
+		if err != nil || !res.Numerical || math.Abs(res.Value-c.want) > 1e-3*math.Max(1, math.Abs(c.want)) {
+			ts := c.term.Tokenise()
+			t.Logf("Limit failed on numerical case: (%s) at %v\nWanted: %v\nGot:    %v (%v)\n", ts.String(), c.x0, c.want, res, err)
+			t.Fail()
+		}
+	}
+
+	// Oscillating terms have no limit, however close the numerical fallback gets
+	oscillating := []struct {
+		term Term
+		x0   float64
+		dir  Direction
+	}{
+		{Sin{X{}}, math.Inf(1), FromBoth},
+		{Sin{Div{S(1), X{}}}, 0, FromAbove},
+		{Mul{X{}, Cos{X{}}}, math.Inf(-1), FromBoth},
+	}
+
+	for _, c := range oscillating {
+		if res, err := Limit(c.term, c.x0, c.dir); err == nil {
+			ts := c.term.Tokenise()
+			t.Logf("Limit of (%s) at %v should not exist\nGot: %v\n", ts.String(), c.x0, res)
+			t.Fail()
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	testCases := []struct {
+		eq   Equation
+		want []Solution
+	}{
+		{Equation{Add{TP{X{}, 2}, X{}}, S(2)}, []Solution{{Value: -2}, {Value: 1}}},
+		{Equation{Prod{Sub{X{}, S(1)}, Sub{X{}, S(2)}, Sub{X{}, S(3)}, Sub{X{}, S(4)}}, S(0)}, []Solution{{Value: 1}, {Value: 2}, {Value: 3}, {Value: 4}}},
+		{Equation{Add{Ln{Add{X{}, S(1)}}, S(1)}, S(3)}, []Solution{{Value: math.Exp(2) - 1}}},
+		{Equation{Sin{Sx{2}}, S(0.5)}, []Solution{{Value: math.Pi / 12, Period: math.Pi}, {Value: 5 * math.Pi / 12, Period: math.Pi}}},
+		{Equation{Cos{X{}}, X{}}, []Solution{{Value: 0.7390851332151607}}},
+	}
+
+	for _, c := range testCases {
+		got, err := Solve(c.eq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ok := len(got) == len(c.want)
+		for i := 0; ok && i < len(got); i++ {
+			ok = math.Abs(got[i].Value-c.want[i].Value) < 1e-9 && math.Abs(got[i].Period-c.want[i].Period) < 1e-9
+		}
+
+		if !ok {
+			ls, rs := c.eq.L.Tokenise(), c.eq.R.Tokenise()
+			t.Logf("Solve failed on case: (%s= %s)\nWanted: %v\nGot:    %v\n", ls.String(), rs.String(), c.want, got)
+			t.Fail()
+		}
+	}
+
+	for _, eq := range []Equation{{Div{X{}, S(0)}, S(2)}, {Div{Sin{X{}}, S(0)}, S(2)}} {
+		if got, err := Solve(eq); err == nil {
+			ls := eq.L.Tokenise()
+			t.Logf("Solve should fail dividing by 0: (%s)\nGot: %v\n", ls.String(), got)
+			t.Fail()
+		}
+	}
+}
+
+func TestEvalInterval(t *testing.T) {
+	testCases := []struct {
+		term   Term
+		x      Interval
+		lo, hi float64
+	}{
+		{Sin{X{}}, Interval{0, math.Pi}, 0, 1},
+		{TP{X{}, 2}, Interval{-1, 2}, 0, 4},
+		{Div{S(1), X{}}, Interval{1, 2}, 0.5, 1},
+		{Div{S(1), X{}}, Interval{0, 3}, 1.0 / 3, math.Inf(1)},
+		{Div{S(1), X{}}, Interval{-3, 0}, math.Inf(-1), -1.0 / 3},
+		{Tan{X{}}, Interval{1, 2}, math.Inf(-1), math.Inf(1)},
+		{Greater{X{}, S(0), S(1), S(-1)}, Interval{1, 2}, 1, 1},
+		{Greater{X{}, S(0), S(1), S(-1)}, Interval{-1, 2}, -1, 1},
+	}
+
+	for _, c := range testCases {
+		got := EvalInterval(c.term, c.x)
+
+		// The bounds are rounded outwards, so they should be just outside of the exact range
+		if !got.Contains(c.lo) || !got.Contains(c.hi) || math.Abs(got.Lo-c.lo) > 1e-9 || math.Abs(got.Hi-c.hi) > 1e-9 && !math.IsInf(c.hi, 0) {
+			ts := c.term.Tokenise()
+			t.Logf("EvalInterval failed on case: (%s) over %v\nWanted: [%v, %v]\nGot:    %v\n", ts.String(), c.x, c.lo, c.hi, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestEvalComplex(t *testing.T) {
+	testCases := []struct {
+		term Term
+		z    complex128
+		want complex128
+	}{
+		{Ln{X{}}, -1, complex(0, math.Pi)},
+		{TP{X{}, 0.5}, -4, 2i},
+		{TP{X{}, 3}, -2, -8},
+		{Exp{Prod{S(math.Pi), X{}}}, 1i, -1},
+		{PT{-1, X{}}, 0.5, 1i},
+		{Add{Sin{X{}}, Cos{X{}}}, 0.3, complex(math.Sin(0.3)+math.Cos(0.3), 0)},
+		{Greater{X{}, S(0), S(1), S(-1)}, complex(1, -5), 1},
+		{Erf{X{}}, 1 + 1i, 1.3161512816979477 + 0.19045346923783471i},
+		{Erf{X{}}, 2 + 3i, -20.829461427614568 + 8.6873182714701631i},
+		{Erfc{X{}}, -1 - 1i, 2.3161512816979477 + 0.19045346923783471i},
+		{Gamma{X{}}, 1i, -0.15494982830181068 - 0.49801566811835604i},
+		{LogGamma{X{}}, 1 + 1i, -0.6509231993018563 - 0.3016403204675331i},
+		{Sub{LogGamma{Add{X{}, S(1)}}, LogGamma{X{}}}, -2.5 + 0.1i, cmplx.Log(-2.5 + 0.1i)},
+		{Digamma{X{}}, 1i, 0.0946503206224770 + 2.0766740474685811i},
+		{Add{Polygamma{1, X{}}, Polygamma{1, Sub{S(1), X{}}}}, 0.3 + 0.7i, math.Pi * math.Pi / cmplx.Pow(cmplx.Sin(math.Pi*(0.3+0.7i)), 2)},
+		{Beta{X{}, S(1)}, 1 + 1i, 0.5 - 0.5i},
+		{BesselJ{0, X{}}, 1 + 1i, 0.9376084768060293 - 0.4965299476091221i},
+		{BesselJ{0, X{}}, 2i, 2.2795853023360673},
+		{BesselY{0, X{}}, -2, complex(math.Y0(2), 2*math.J0(2))},
+		{Sub{Mul{BesselJ{1, X{}}, BesselY{0, X{}}}, Mul{BesselJ{0, X{}}, BesselY{1, X{}}}}, -3 + 2i, 2 / (math.Pi * (-3 + 2i))},
+		{Sub{Mul{BesselJ{1, X{}}, BesselY{0, X{}}}, Mul{BesselJ{0, X{}}, BesselY{1, X{}}}}, 20 + 5i, 2 / (math.Pi * (20 + 5i))},
+	}
+
+	for _, c := range testCases {
+		got := EvalComplex(c.term, c.z)
+
+		if cmplx.Abs(got-c.want) > 1e-9 {
+			ts := c.term.Tokenise()
+			t.Logf("EvalComplex failed on case: (%s) at %v\nWanted: %v\nGot:    %v\n", ts.String(), c.z, c.want, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestEvalChecked(t *testing.T) {
+	testCases := []struct {
+		term   Term
+		x      float64
+		strict bool
+		err    error
+		path   []int
+	}{
+		{Add{S(1), Ln{X{}}}, -1, false, ErrDomain, []int{1}},
+		{Mul{S(2), Div{S(1), Sub{X{}, S(1)}}}, 1, false, ErrPole, []int{1}},
+		{Sum{X{}, Tan{Sx{0.5}}}, math.Pi, false, ErrPole, []int{1}},
+		{Exp{TP{X{}, 2}}, 100, false, ErrOverflow, []int{}},
+		{Div{S(1), Div{S(1), X{}}}, 0, false, nil, nil},
+		{Div{S(1), Div{S(1), X{}}}, 0, true, ErrPole, []int{1}},
+		{Sin{X{}}, 1, true, nil, nil},
+	}
+
+	for _, c := range testCases {
+		var err error
+		if c.strict {
+			_, err = EvalStrict(c.term, c.x)
+		} else {
+			_, err = EvalChecked(c.term, c.x)
+		}
+
+		var evalErr *EvalError
+		ok := errors.Is(err, c.err)
+		if c.err != nil {
+			ok = ok && errors.As(err, &evalErr) && reflect.DeepEqual(evalErr.Path, c.path)
+		}
+
+		if !ok {
+			ts := c.term.Tokenise()
+			t.Logf("EvalChecked failed on case: (%s) at %v\nWanted: %v at %v\nGot:    %v\n", ts.String(), c.x, c.err, c.path, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestDomain(t *testing.T) {
+	testCases := []struct {
+		term   Term
+		x      Interval
+		domain []Interval
+		poles  []float64
+	}{
+		{Div{S(1), X{}}, Interval{-1, 1}, []Interval{{-1, 0}, {0, 1}}, []float64{0}},
+		{Ln{Sub{S(1), TP{X{}, 2}}}, Interval{-2, 2}, []Interval{{-1, 1}}, []float64{-1, 1}},
+		{Tan{X{}}, Interval{0, 5}, []Interval{{0, math.Pi / 2}, {math.Pi / 2, 3 * math.Pi / 2}, {3 * math.Pi / 2, 5}}, []float64{math.Pi / 2, 3 * math.Pi / 2}},
+		{Div{Sin{X{}}, X{}}, Interval{-1, 1}, []Interval{{-1, 0}, {0, 1}}, []float64{}},
+		{TP{X{}, 0.5}, Interval{-1, 1}, []Interval{{0, 1}}, []float64{}},
+		{Greater{X{}, S(0), Div{S(1), Sub{X{}, S(0.5)}}, S(0)}, Interval{-1, 1}, []Interval{{-1, 0.5}, {0.5, 1}}, []float64{0.5}},
+		{Gamma{X{}}, Interval{-2.5, 1}, []Interval{{-2.5, -2}, {-2, -1}, {-1, 0}, {0, 1}}, []float64{-2, -1, 0}},
+	}
+
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9
+	}
+
+	for _, c := range testCases {
+		domain := DomainIn(c.term, c.x)
+		poles := Singularities(c.term, c.x)
+
+		ok := len(domain) == len(c.domain) && len(poles) == len(c.poles)
+		for i := 0; ok && i < len(domain); i++ {
+			ok = near(domain[i].Lo, c.domain[i].Lo) && near(domain[i].Hi, c.domain[i].Hi)
+		}
+		for i := 0; ok && i < len(poles); i++ {
+			ok = near(poles[i], c.poles[i])
+		}
+
+		if !ok {
+			ts := c.term.Tokenise()
+			t.Logf("Domain failed on case: (%s) over %v\nWanted: %v, %v\nGot:    %v, %v\n", ts.String(), c.x, c.domain, c.poles, domain, poles)
+			t.Fail()
+		}
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	in := Uncertain{Mean: 2, Sigma: 0.1}
+
+	testCases := []struct {
+		term      Term
+		linear    Estimate
+		quadratic Estimate
+		tol       float64
+	}{
+		{Add{Sx{3}, S(1)}, Estimate{7, 0.09}, Estimate{7, 0.09}, 1e-2},
+		{TP{X{}, 2}, Estimate{4, 0.16}, Estimate{4.01, 0.1602}, 1e-2},
+		{Exp{X{}}, Estimate{math.Exp(2), math.Exp(4) * 0.01}, Estimate{math.Exp(2) * 1.005, math.Exp(4) * (0.01 + 0.00005)}, 2e-2},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	for _, c := range testCases {
+		linear, err := PropagateLinear(c.term, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		quadratic, err := PropagateQuadratic(c.term, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		carlo, err := PropagateMonteCarlo(c.term, in, 100000, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		near := func(a, b Estimate, tol float64) bool {
+			return math.Abs(a.Mean-b.Mean) <= tol*math.Abs(b.Mean) && math.Abs(a.Variance-b.Variance) <= tol*b.Variance
+		}
+
+		// Monte carlo should agree with the second order estimate, which is close to exact for these
+		if !near(linear, c.linear, 1e-9) || !near(quadratic, c.quadratic, 1e-9) || !near(carlo, c.quadratic, c.tol) {
+			ts := c.term.Tokenise()
+			t.Logf("Propagate failed on case: (%s)\nWanted: %v, %v\nGot:    %v, %v, %v\n", ts.String(), c.linear, c.quadratic, linear, quadratic, carlo)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,572 @@
+package alg
+
+import (
+	"math"
+)
+
+/*
+NonSmooth defines functions with kinks or jumps:
+  Abs   => The absolute value of a term
+  Sign  => -1, 0 or 1 depending on the sign of a term
+  Min   => The smaller of two terms
+  Max   => The larger of two terms
+  Floor => The largest integer not above a term
+  Ceil  => The smallest integer not below a term
+  Mod   => The remainder of A / B, with the sign of B (so Mod{x, 1} is in [0, 1))
+Derivatives are taken to be the derivative of whichever piece applies, so are undefined exactly at the kinks and jumps.
+*/
+
+func init() {
+	register(TidAbs, Registration{
+		Name:  "abs",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Abs{X: args[0]}, nil
+		},
+		Type: Abs{},
+	})
+	register(TidSign, Registration{
+		Name:  "sign",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sign{X: args[0]}, nil
+		},
+		Type: Sign{},
+	})
+	register(TidMin, Registration{
+		Name:  "min",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Min{A: args[0], B: args[1]}, nil
+		},
+		Type: Min{},
+	})
+	register(TidMax, Registration{
+		Name:  "max",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Max{A: args[0], B: args[1]}, nil
+		},
+		Type: Max{},
+	})
+	register(TidFloor, Registration{
+		Name:  "floor",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Floor{X: args[0]}, nil
+		},
+		Type: Floor{},
+	})
+	register(TidCeil, Registration{
+		Name:  "ceil",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Ceil{X: args[0]}, nil
+		},
+		Type: Ceil{},
+	})
+	register(TidMod, Registration{
+		Name:  "mod",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Mod{A: args[0], B: args[1]}, nil
+		},
+		Type: Mod{},
+	})
+}
+
+type Abs struct {
+	X Term
+}
+
+func (e Abs) Tokenise() Tokens {
+	t := Tokens{{id: TidAbs}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Abs) E(x float64) float64 {
+	return math.Abs(e.X.E(x))
+}
+
+func (e Abs) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		Sign{e.X},
+	}.T()
+}
+
+func (e Abs) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Abs(val))
+	}
+
+	if inner, ok := e.X.(Abs); ok {
+		return inner.T()
+	}
+
+	return Abs{e.X}
+}
+
+func (e Abs) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Abs(val)
+}
+
+func (e Abs) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Abs) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Sign struct {
+	X Term
+}
+
+func (e Sign) Tokenise() Tokens {
+	t := Tokens{{id: TidSign}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Sign) E(x float64) float64 {
+	return sgn(e.X.E(x))
+}
+
+func (e Sign) Dx() Term {
+	return S(0)
+}
+
+func (e Sign) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(sgn(val))
+	}
+
+	if inner, ok := e.X.(Sign); ok {
+		return inner.T()
+	}
+
+	return Sign{e.X}
+}
+
+func (e Sign) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, sgn(val)
+}
+
+func (e Sign) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Sign) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Min struct {
+	A, B Term
+}
+
+func (e Min) Tokenise() Tokens {
+	t := Tokens{{id: TidMin}}
+	t = append(t, e.A.Tokenise()...)
+	t = append(t, e.B.Tokenise()...)
+	return t
+}
+
+func (e Min) E(x float64) float64 {
+	return math.Min(e.A.E(x), e.B.E(x))
+}
+
+func (e Min) Dx() Term {
+	return LessEqual{
+		A:    e.A,
+		B:    e.B,
+		If:   e.A.Dx(),
+		Else: e.B.Dx(),
+	}.T()
+}
+
+func (e Min) T() Term {
+	e = simplified(e)
+
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if aok && bok {
+		return S(math.Min(av, bv))
+	}
+
+	a, b := e.A, e.B
+	if key(a) == key(b) {
+		return a
+	}
+
+	return Min{a, b}
+}
+
+func (e Min) Is() (bool, float64) {
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if !aok || !bok {
+		return false, 0
+	}
+
+	return true, math.Min(av, bv)
+}
+
+func (e Min) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e Min) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
+type Max struct {
+	A, B Term
+}
+
+func (e Max) Tokenise() Tokens {
+	t := Tokens{{id: TidMax}}
+	t = append(t, e.A.Tokenise()...)
+	t = append(t, e.B.Tokenise()...)
+	return t
+}
+
+func (e Max) E(x float64) float64 {
+	return math.Max(e.A.E(x), e.B.E(x))
+}
+
+func (e Max) Dx() Term {
+	return GreaterEqual{
+		A:    e.A,
+		B:    e.B,
+		If:   e.A.Dx(),
+		Else: e.B.Dx(),
+	}.T()
+}
+
+func (e Max) T() Term {
+	e = simplified(e)
+
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if aok && bok {
+		return S(math.Max(av, bv))
+	}
+
+	a, b := e.A, e.B
+	if key(a) == key(b) {
+		return a
+	}
+
+	return Max{a, b}
+}
+
+func (e Max) Is() (bool, float64) {
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if !aok || !bok {
+		return false, 0
+	}
+
+	return true, math.Max(av, bv)
+}
+
+func (e Max) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e Max) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
+type Floor struct {
+	X Term
+}
+
+func (e Floor) Tokenise() Tokens {
+	t := Tokens{{id: TidFloor}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Floor) E(x float64) float64 {
+	return math.Floor(e.X.E(x))
+}
+
+func (e Floor) Dx() Term {
+	return S(0)
+}
+
+func (e Floor) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Floor(val))
+	}
+
+	// Rounding something that is already an integer does nothing
+	switch inner := e.X.(type) {
+	case Floor, Ceil, Sign:
+		return inner.T()
+	}
+
+	return Floor{e.X}
+}
+
+func (e Floor) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Floor(val)
+}
+
+func (e Floor) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Floor) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Ceil struct {
+	X Term
+}
+
+func (e Ceil) Tokenise() Tokens {
+	t := Tokens{{id: TidCeil}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Ceil) E(x float64) float64 {
+	return math.Ceil(e.X.E(x))
+}
+
+func (e Ceil) Dx() Term {
+	return S(0)
+}
+
+func (e Ceil) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Ceil(val))
+	}
+
+	switch inner := e.X.(type) {
+	case Floor, Ceil, Sign:
+		return inner.T()
+	}
+
+	return Ceil{e.X}
+}
+
+func (e Ceil) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Ceil(val)
+}
+
+func (e Ceil) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Ceil) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Mod struct {
+	A, B Term
+}
+
+func (e Mod) Tokenise() Tokens {
+	t := Tokens{{id: TidMod}}
+	t = append(t, e.A.Tokenise()...)
+	t = append(t, e.B.Tokenise()...)
+	return t
+}
+
+func (e Mod) E(x float64) float64 {
+	return mod(e.A.E(x), e.B.E(x))
+}
+
+func (e Mod) Dx() Term {
+	// a mod b = a - b floor(a / b), and the floor is constant between jumps
+	return Sub{
+		e.A.Dx(),
+		Mul{
+			e.B.Dx(),
+			Floor{Div{e.A, e.B}},
+		},
+	}.T()
+}
+
+func (e Mod) T() Term {
+	e = simplified(e)
+
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if aok && bok {
+		return S(mod(av, bv))
+	}
+
+	return Mod{e.A, e.B}
+}
+
+func (e Mod) Is() (bool, float64) {
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if !aok || !bok {
+		return false, 0
+	}
+
+	return true, mod(av, bv)
+}
+
+func (e Mod) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e Mod) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
+// SimplifyIn simplifies a term knowing that x stays in an interval.
+// Non-smooth terms and conditionals whose piece is decided over the whole interval are replaced by that piece, so Abs{x} over [1, 2] becomes x.
+func SimplifyIn(term Term, x Interval) Term {
+	switch e := term.(type) {
+	case Abs:
+		a := SimplifyIn(e.X, x)
+		if r := EvalInterval(a, x); r.Lo >= 0 {
+			return a
+		} else if r.Hi <= 0 {
+			return Prod{S(-1), a}.T()
+		}
+		return Abs{a}
+	case Sign:
+		a := SimplifyIn(e.X, x)
+		if r := EvalInterval(a, x); r.Lo > 0 {
+			return S(1)
+		} else if r.Hi < 0 {
+			return S(-1)
+		} else if r.Lo == 0 && r.Hi == 0 {
+			return S(0)
+		}
+		return Sign{a}
+	case Min:
+		a, b := SimplifyIn(e.A, x), SimplifyIn(e.B, x)
+		if ra, rb := EvalInterval(a, x), EvalInterval(b, x); ra.Hi <= rb.Lo {
+			return a
+		} else if rb.Hi <= ra.Lo {
+			return b
+		}
+		return Min{a, b}.T()
+	case Max:
+		a, b := SimplifyIn(e.A, x), SimplifyIn(e.B, x)
+		if ra, rb := EvalInterval(a, x), EvalInterval(b, x); ra.Lo >= rb.Hi {
+			return a
+		} else if rb.Lo >= ra.Hi {
+			return b
+		}
+		return Max{a, b}.T()
+	case Floor:
+		a := SimplifyIn(e.X, x)
+		if r := EvalInterval(a, x); math.Floor(r.Lo) == math.Floor(r.Hi) {
+			return S(math.Floor(r.Lo))
+		}
+		return Floor{a}.T()
+	case Ceil:
+		a := SimplifyIn(e.X, x)
+		if r := EvalInterval(a, x); math.Ceil(r.Lo) == math.Ceil(r.Hi) {
+			return S(math.Ceil(r.Lo))
+		}
+		return Ceil{a}.T()
+	case Mod:
+		a, b := SimplifyIn(e.A, x), SimplifyIn(e.B, x)
+		if ok, bv := b.Is(); ok && bv != 0 {
+			r := EvalInterval(a, x)
+			if n := math.Floor(r.Lo / bv); n == math.Floor(r.Hi/bv) {
+				return Sub{a, S(n * bv)}.T()
+			}
+		}
+		return Mod{a, b}.T()
+	}
+
+	if ifTrue, ifFalse, rebuild, ok := branches(term); ok {
+		if always, never := decide(term, x); always {
+			return SimplifyIn(ifTrue, x)
+		} else if never {
+			return SimplifyIn(ifFalse, x)
+		}
+
+		return rebuild(SimplifyIn(ifTrue, x), SimplifyIn(ifFalse, x)).T()
+	}
+
+	if p, ok := term.(Parent); ok {
+		children := p.Children()
+		for i, child := range children {
+			children[i] = SimplifyIn(child, x)
+		}
+		return p.WithChildren(children).T()
+	}
+
+	return term.T()
+}
+
+// sgn returns -1, 0 or 1 depending on the sign of v, and NaN for NaN
+func sgn(v float64) float64 {
+	if v > 0 {
+		return 1
+	} else if v < 0 {
+		return -1
+	}
+
+	return v
+}
+
+// mod returns the remainder of a / b with the sign of b
+func mod(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+
+	return r
+}
@@ -0,0 +1,342 @@
+package ode
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/e74000/alg"
+)
+
+/*
+Ode solves initial value problems dy/dt = F(t, y) where F is a term:
+  RK4           => Classic fixed step runge-kutta
+  DormandPrince => Adaptive explicit 5(4) runge-kutta, for non-stiff problems
+  Rosenbrock    => Adaptive linearly implicit ROS2, for stiff problems, using the symbolic jacobian F.Dx()
+Terms only have the one variable X, so it stands for y, and F uses Time for t.
+Every solver stops a step at each point where a conditional term (Greater, Range, ...) in F changes branch, and records it as an Event.
+Kinks and jumps of the non-smooth terms (Abs, Floor, Mod, ...) are treated the same way.
+*/
+
+// Problem is the initial value problem dy/dt = F(t, y), y(T0) = Y0.
+// X is y in F and Time is t, so y' = t y is Problem{F: alg.Mul{A: Time{}, B: alg.X{}}}.
+type Problem struct {
+	F alg.Term
+
+	// G is added to F, and is a term in t alone where X stands for t. It may be nil.
+	//
+	// Deprecated: Use Time in F instead, since X means y there and t here.
+	G alg.Term
+
+	T0, Y0 float64
+}
+
+// Event is a point where one of the conditional terms of a problem switched branch
+type Event struct {
+	T, Y float64
+	Cond alg.Term
+}
+
+// Solution is the list of steps taken by a solver, the derivative at each step is kept for dense output.
+type Solution struct {
+	T, Y, DY []float64
+	Events   []Event
+}
+
+// At returns y(t) anywhere in the solved range, using cubic hermite interpolation between steps
+func (s *Solution) At(t float64) (float64, error) {
+	n := len(s.T)
+	if n == 0 || t < s.T[0] || t > s.T[n-1] {
+		return 0, errors.New("t is outside of the solution")
+	}
+
+	i := sort.SearchFloat64s(s.T, t)
+	if s.T[i] == t {
+		return s.Y[i], nil
+	}
+
+	return hermite(s.T[i-1], s.T[i], s.Y[i-1], s.Y[i], s.DY[i-1], s.DY[i], t), nil
+}
+
+// hermite interpolates the cubic through (t0, y0) and (t1, y1) with gradients d0 and d1
+func hermite(t0, t1, y0, y1, d0, d1, t float64) float64 {
+	h := t1 - t0
+	u := (t - t0) / h
+
+	h00 := (1 + 2*u) * (1 - u) * (1 - u)
+	h10 := u * (1 - u) * (1 - u)
+	h01 := u * u * (3 - 2*u)
+	h11 := u * u * (u - 1)
+
+	return h00*y0 + h10*h*d0 + h01*y1 + h11*h*d1
+}
+
+// full returns the whole right hand side as a term in y and Time, with G written in terms of Time
+func (p Problem) full() alg.Term {
+	if p.G == nil {
+		return p.F
+	}
+
+	return alg.Add{A: p.F, B: alg.Substitute(p.G, Time{})}
+}
+
+// dydt returns dy/dt as a function of t and y
+func (p Problem) dydt() func(t, y float64) float64 {
+	return newRHS(p.full()).at
+}
+
+// stepper advances y by a step of h from t, returning the new value and an error estimate.
+type stepper func(t, y, h float64) (float64, float64)
+
+// RK4 solves a problem up to t1 with n fixed steps of the classic runge-kutta method.
+func RK4(p Problem, t1 float64, n int) (*Solution, error) {
+	if n <= 0 {
+		return nil, errors.New("number of steps must be positive")
+	}
+
+	f := p.dydt()
+
+	step := func(t, y, h float64) (float64, float64) {
+		k1 := f(t, y)
+		k2 := f(t+h/2, y+h/2*k1)
+		k3 := f(t+h/2, y+h/2*k2)
+		k4 := f(t+h, y+h*k3)
+
+		return y + h/6*(k1+2*k2+2*k3+k4), 0
+	}
+
+	return integrate(p, t1, (t1-p.T0)/float64(n), math.Inf(1), 4, step)
+}
+
+// DormandPrince solves a problem up to t1, adapting the step size to keep the local error below tol.
+func DormandPrince(p Problem, t1, tol float64) (*Solution, error) {
+	const (
+		a21                     = 1.0 / 5
+		a31, a32                = 3.0 / 40, 9.0 / 40
+		a41, a42, a43           = 44.0 / 45, -56.0 / 15, 32.0 / 9
+		a51, a52, a53, a54      = 19372.0 / 6561, -25360.0 / 2187, 64448.0 / 6561, -212.0 / 729
+		a61, a62, a63, a64, a65 = 9017.0 / 3168, -355.0 / 33, 46732.0 / 5247, 49.0 / 176, -5103.0 / 18656
+		b1, b3, b4, b5, b6      = 35.0 / 384, 500.0 / 1113, 125.0 / 192, -2187.0 / 6784, 11.0 / 84
+		e1, e3, e4, e5, e6, e7  = 71.0 / 57600, -71.0 / 16695, 71.0 / 1920, -17253.0 / 339200, 22.0 / 525, -1.0 / 40
+	)
+
+	f := p.dydt()
+
+	step := func(t, y, h float64) (float64, float64) {
+		k1 := f(t, y)
+		k2 := f(t+h/5, y+h*(a21*k1))
+		k3 := f(t+3*h/10, y+h*(a31*k1+a32*k2))
+		k4 := f(t+4*h/5, y+h*(a41*k1+a42*k2+a43*k3))
+		k5 := f(t+8*h/9, y+h*(a51*k1+a52*k2+a53*k3+a54*k4))
+		k6 := f(t+h, y+h*(a61*k1+a62*k2+a63*k3+a64*k4+a65*k5))
+
+		next := y + h*(b1*k1+b3*k3+b4*k4+b5*k5+b6*k6)
+		k7 := f(t+h, next)
+
+		return next, math.Abs(h * (e1*k1 + e3*k3 + e4*k4 + e5*k5 + e6*k6 + e7*k7))
+	}
+
+	return integrate(p, t1, (t1-p.T0)/100, tol, 5, step)
+}
+
+// Rosenbrock solves a stiff problem up to t1 with the second order ROS2 method, adapting the step size to keep the local error below tol.
+// Each step solves a linear system with the jacobian F.Dx(), rather than iterating like a fully implicit method.
+func Rosenbrock(p Problem, t1, tol float64) (*Solution, error) {
+	gamma := 1 + 1/math.Sqrt2
+
+	f := p.dydt()
+	jac := newRHS(p.full().Dx())
+
+	// dF/dt is the derivative of F with t and y swapped, swapped back
+	dft := newRHS(swap(swap(p.full()).Dx()))
+
+	step := func(t, y, h float64) (float64, float64) {
+		w := 1 - gamma*h*jac.at(t, y)
+		ft := gamma * h * dft.at(t, y)
+
+		k1 := (f(t, y) + ft) / w
+		k2 := (f(t+h, y+h*k1) - 2*k1 - ft) / w
+
+		// The embedded linearly implicit euler step is y + h k1
+		return y + h*(1.5*k1+0.5*k2), math.Abs(h * (0.5*k1 + 0.5*k2))
+	}
+
+	return integrate(p, t1, (t1-p.T0)/100, tol, 2, step)
+}
+
+// integrate drives a stepper from T0 to t1, starting with a step of h.
+// Steps with an error above tol are retried with a smaller step, and order is used to pick the next step size.
+func integrate(p Problem, t1, h, tol float64, order int, step stepper) (*Solution, error) {
+	if p.F == nil {
+		return nil, errors.New("problem has no right hand side")
+	}
+
+	if !(t1 > p.T0) {
+		return nil, errors.New("t1 must be after the start of the problem")
+	}
+
+	conds := conditions(p.full())
+
+	f := p.dydt()
+
+	t, y := p.T0, p.Y0
+	sol := &Solution{
+		T:  []float64{t},
+		Y:  []float64{y},
+		DY: []float64{f(t, y)},
+	}
+
+	minStep := 1e-12 * math.Max(1, math.Abs(t1-p.T0))
+	adaptive := !math.IsInf(tol, 0)
+
+	// skip is the condition that ended the last step, so that it isn't detected again at the start of the next
+	skip := -1
+
+	for t < t1 {
+		h = math.Min(h, t1-t)
+
+		next, err := step(t, y, h)
+		if math.IsNaN(next) || math.IsInf(next, 0) {
+			return sol, errors.New("solution is not finite")
+		}
+
+		taken := h
+
+		if adaptive {
+			scale := 0.9 * math.Pow(tol/math.Max(err, 1e-300), 1/float64(order))
+			scale = math.Max(0.2, math.Min(5, scale))
+
+			h *= scale
+			if err > tol {
+				if h < minStep {
+					return sol, errors.New("step size fell below the minimum")
+				}
+				continue
+			}
+		}
+
+		// Stop the step at the first conditional that changes branch within it
+		dNext := f(t+taken, next)
+		at := func(h float64) float64 {
+			v, _ := step(t, y, h)
+			return v
+		}
+
+		if i, te, ok := firstEvent(conds, skip, t, y, t+taken, next, at, minStep); ok {
+			if te < t+taken {
+				taken = te - t
+				next = at(taken)
+				dNext = f(te, next)
+			}
+
+			sol.Events = append(sol.Events, Event{
+				T:    te,
+				Y:    next,
+				Cond: conds[i].term,
+			})
+			skip = i
+		} else {
+			skip = -1
+		}
+
+		t, y = t+taken, next
+		sol.T = append(sol.T, t)
+		sol.Y = append(sol.Y, y)
+		sol.DY = append(sol.DY, dNext)
+	}
+
+	return sol, nil
+}
+
+// cond is a function of t and y that changes sign when a conditional term switches branch
+type cond struct {
+	term alg.Term
+	g    rhs
+}
+
+// firstEvent finds the earliest condition that changes sign over a step from (t0, y0) to (t1, y1).
+// The crossing is located by bisecting the length of the step, using at to take a step of a given length from t0.
+// It is reported just after the switch, and the condition at index skip is ignored if it crosses right at the start of the step.
+func firstEvent(conds []cond, skip int, t0, y0, t1, y1 float64, at func(h float64) float64, minStep float64) (int, float64, bool) {
+	first, te, found := -1, t1, false
+
+	for i, c := range conds {
+		g0, g1 := c.g.at(t0, y0), c.g.at(t1, y1)
+		if g0 == 0 || g0*g1 > 0 {
+			continue
+		}
+
+		lo, hi := t0, math.Min(t1, te)
+		for hi-lo > minStep {
+			mid := (lo + hi) / 2
+			gm := c.g.at(mid, at(mid-t0))
+
+			if gm*g0 > 0 {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+
+		if i == skip && hi-t0 <= 2*minStep {
+			continue
+		}
+
+		if !found || hi < te {
+			first, te, found = i, hi, true
+		}
+	}
+
+	return first, te, found
+}
+
+// conditions finds the switching functions of every conditional and non-smooth term inside a term
+func conditions(term alg.Term) []cond {
+	out := make([]cond, 0)
+	add := func(e, g alg.Term) {
+		out = append(out, cond{e, newRHS(g)})
+	}
+
+	alg.Walk(term, func(term alg.Term) bool {
+		switch e := term.(type) {
+		case alg.Greater:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.Less:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.GreaterEqual:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.LessEqual:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.Equal:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.NotEqual:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.Range:
+			add(e, alg.Sub{A: e.X, B: e.A})
+			add(e, alg.Sub{A: e.X, B: e.B})
+		case alg.Abs:
+			add(e, e.X)
+		case alg.Sign:
+			add(e, e.X)
+		case alg.Min:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.Max:
+			add(e, alg.Sub{A: e.A, B: e.B})
+		case alg.Floor:
+			// sin(pi x) changes sign at every integer, which is where floor and ceil jump
+			add(e, alg.Sin{X: alg.Prod{alg.S(math.Pi), e.X}})
+		case alg.Ceil:
+			add(e, alg.Sin{X: alg.Prod{alg.S(math.Pi), e.X}})
+		case alg.Mod:
+			add(e, alg.Sin{X: alg.Prod{alg.S(math.Pi), alg.Div{N: e.A, D: e.B}}})
+		case alg.Call:
+			// The body already contains the argument, so there's no need to walk it again
+			out = append(out, conditions(e.Inline())...)
+			return false
+		}
+
+		return true
+	})
+
+	return out
+}
@@ -0,0 +1,143 @@
+package ode
+
+import (
+	"math"
+	"testing"
+
+	"github.com/e74000/alg"
+)
+
+func TestSolvers(t *testing.T) {
+	// dy/dt = -y has the solution e^-t
+	p := Problem{F: alg.Sx{S: -1}, T0: 0, Y0: 1}
+
+	solvers := map[string]func() (*Solution, error){
+		"RK4":           func() (*Solution, error) { return RK4(p, 2, 50) },
+		"DormandPrince": func() (*Solution, error) { return DormandPrince(p, 2, 1e-9) },
+		"Rosenbrock":    func() (*Solution, error) { return Rosenbrock(p, 2, 1e-7) },
+	}
+
+	for name, solve := range solvers {
+		sol, err := solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, at := range []float64{0.5, 1.3, 2} {
+			y, err := sol.At(at)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if math.Abs(y-math.Exp(-at)) > 1e-5 {
+				t.Logf("%s failed at %v\nWanted: %v\nGot:    %v\n", name, at, math.Exp(-at), y)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	// dy/dt = t y has the solution e^(t^2 / 2), which can't be split into F(y) + G(t)
+	p := Problem{F: alg.Mul{A: Time{}, B: alg.X{}}, T0: 0, Y0: 1}
+
+	solvers := map[string]func() (*Solution, error){
+		"RK4":           func() (*Solution, error) { return RK4(p, 1.5, 100) },
+		"DormandPrince": func() (*Solution, error) { return DormandPrince(p, 1.5, 1e-9) },
+		"Rosenbrock":    func() (*Solution, error) { return Rosenbrock(p, 1.5, 1e-7) },
+	}
+
+	for name, solve := range solvers {
+		sol, err := solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, at := range []float64{0.5, 1, 1.5} {
+			y, err := sol.At(at)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := math.Exp(at * at / 2); math.Abs(y-want) > 1e-4*want {
+				t.Logf("%s failed at %v\nWanted: %v\nGot:    %v\n", name, at, want, y)
+				t.Fail()
+			}
+		}
+	}
+
+	// Conditions in F can depend on t as well as y
+	p = Problem{F: alg.Greater{A: alg.Add{A: Time{}, B: alg.X{}}, B: alg.S(3), If: alg.S(0), Else: alg.S(1)}, T0: 0, Y0: 0}
+
+	sol, err := DormandPrince(p, 3, 1e-8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sol.Events) != 1 || math.Abs(sol.Events[0].T-1.5) > 1e-6 {
+		t.Logf("Time failed to find the event at t + y = 3\nWanted: 1.5\nGot:    %v\n", sol.Events)
+		t.Fail()
+	}
+
+	ts, err := alg.Tokenise("* ode.t x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if term, err := ts.Parse(); err != nil || !alg.Same(term, alg.Mul{A: Time{}, B: alg.X{}}) {
+		t.Logf("Time failed to parse \"* ode.t x\": %v\n", err)
+		t.Fail()
+	}
+
+	if _, err := alg.Tokenise("t"); err == nil {
+		t.Log("Time took the token name \"t\" from every program importing ode")
+		t.Fail()
+	}
+}
+
+func TestEvents(t *testing.T) {
+	// y grows at 1 until it reaches 1, then at 2, with an extra 5 while 2 <= t <= 2.5
+	p := Problem{
+		F: alg.Add{
+			A: alg.Greater{A: alg.X{}, B: alg.S(1), If: alg.S(2), Else: alg.S(1)},
+			B: alg.Range{X: Time{}, A: alg.S(2), B: alg.S(2.5), If: alg.S(5), Else: alg.S(0)},
+		},
+		T0: 0,
+		Y0: 0,
+	}
+
+	sol, err := DormandPrince(p, 3, 1e-8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float64{1, 2, 2.5}
+	if len(sol.Events) != len(want) {
+		t.Fatalf("Wanted %d events, got %d\n", len(want), len(sol.Events))
+	}
+
+	for i, ev := range sol.Events {
+		if math.Abs(ev.T-want[i]) > 1e-6 {
+			t.Logf("Event %d at the wrong time\nWanted: %v\nGot:    %v\n", i, want[i], ev.T)
+			t.Fail()
+		}
+	}
+
+	// The deprecated G, where X is t, describes the same problem
+	p = Problem{
+		F:  alg.Greater{A: alg.X{}, B: alg.S(1), If: alg.S(2), Else: alg.S(1)},
+		G:  alg.Range{X: alg.X{}, A: alg.S(2), B: alg.S(2.5), If: alg.S(5), Else: alg.S(0)},
+		T0: 0,
+		Y0: 0,
+	}
+
+	old, err := DormandPrince(p, 3, 1e-8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(old.Events) != len(sol.Events) || math.Abs(old.Y[len(old.Y)-1]-sol.Y[len(sol.Y)-1]) > 1e-6 {
+		t.Logf("G failed to match the same problem written with Time\nWanted: %v\nGot:    %v\n", sol.Events, old.Events)
+		t.Fail()
+	}
+}
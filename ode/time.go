@@ -0,0 +1,107 @@
+package ode
+
+import (
+	"math"
+
+	"github.com/e74000/alg"
+)
+
+// tidTime is the token ID of Time.
+// It is written "ode.t" rather than "t", since importing this package registers it for every program, and a plain t would clash with other uses.
+var tidTime = alg.Register(alg.Registration{
+	Name:  "ode.t",
+	Arity: 0,
+	New: func(_ float64, _ []alg.Term) (alg.Term, error) {
+		return Time{}, nil
+	},
+	Type: Time{},
+})
+
+// Time stands for t in the right hand side F of a problem, where X stands for y, so that F can depend on both.
+// It is constant as far as Dx is concerned, so F.Dx() is the partial derivative by y.
+// It only has a value inside a problem, and evaluates to NaN anywhere else. It is written ode.t when tokenised.
+type Time struct{}
+
+func (e Time) E(_ float64) float64 {
+	return math.NaN()
+}
+
+func (e Time) Dx() alg.Term {
+	return alg.S(0)
+}
+
+func (e Time) T() alg.Term {
+	return e
+}
+
+func (e Time) Is() (bool, float64) {
+	return false, 0
+}
+
+func (e Time) Tokenise() alg.Tokens {
+	return alg.Tokens{alg.NewToken(tidTime, 0)}
+}
+
+// rhs is a term in y that may also use Time, which is replaced once with a clock so that t can be set without rebuilding the tree.
+// Setting t writes to the clock, so an rhs belongs to a single solver call and mustn't be shared between goroutines.
+type rhs struct {
+	term alg.Term
+	t    *float64
+}
+
+func newRHS(term alg.Term) rhs {
+	t := new(float64)
+
+	return rhs{alg.Map(term, func(term alg.Term) alg.Term {
+		if _, ok := term.(Time); ok {
+			return clock{t}
+		}
+		return term
+	}), t}
+}
+
+// at evaluates the term at t and y
+func (r rhs) at(t, y float64) float64 {
+	*r.t = t
+	return r.term.E(y)
+}
+
+// clock is Time inside an rhs, where it evaluates to the time the rhs was last evaluated at
+type clock struct {
+	t *float64
+}
+
+func (e clock) E(_ float64) float64 {
+	return *e.t
+}
+
+func (e clock) Dx() alg.Term {
+	return alg.S(0)
+}
+
+func (e clock) T() alg.Term {
+	return e
+}
+
+func (e clock) Is() (bool, float64) {
+	return false, 0
+}
+
+func (e clock) Tokenise() alg.Tokens {
+	return Time{}.Tokenise()
+}
+
+// swap exchanges X and Time in a term, so that Dx of the result differentiates by t instead of y
+func swap(term alg.Term) alg.Term {
+	return alg.Map(term, func(term alg.Term) alg.Term {
+		switch e := term.(type) {
+		case alg.X:
+			return Time{}
+		case alg.Sx:
+			return alg.Mul{A: alg.S(e.S), B: Time{}}
+		case Time:
+			return alg.X{}
+		}
+		return term
+	})
+}
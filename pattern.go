@@ -0,0 +1,254 @@
+package alg
+
+import (
+	"math"
+	"strings"
+)
+
+/*
+Pattern defines wildcards, for writing patterns that Match fills in:
+  Wild => A placeholder, named with a prefix saying what it can match:
+          _a matches any term, #c only terms without x, and $v only terms with x
+A wildcard used more than once has to match the same term each time.
+Wildcards tokenise like any other term, so "* #a sin + * #b x #c" parses to a pattern for a sin(b x + c).
+*/
+
+func init() {
+	// Parse builds wildcards from the name in their token, so there's nothing to build them from a value
+	register(TidWild, Registration{
+		Arity: 0,
+		Type:  Wild{},
+	})
+}
+
+// isWild returns true if a string names a wildcard
+func isWild(s string) bool {
+	return len(s) > 1 && strings.ContainsRune("_#$", rune(s[0]))
+}
+
+type Wild struct {
+	Name string
+}
+
+func (e Wild) Tokenise() Tokens {
+	return Tokens{{id: TidWild, name: e.Name}}
+}
+
+func (e Wild) E(_ float64) float64 {
+	return math.NaN()
+}
+
+func (e Wild) Dx() Term {
+	return S(math.NaN())
+}
+
+func (e Wild) T() Term {
+	return e
+}
+
+func (e Wild) Is() (bool, float64) {
+	return false, 0
+}
+
+// accepts returns true if the wildcard's prefix allows it to match a term
+func (e Wild) accepts(term Term) bool {
+	if e.Name == "" {
+		return true
+	}
+
+	switch e.Name[0] {
+	case '#':
+		return !hasX(term)
+	case '$':
+		return hasX(term)
+	}
+
+	return true
+}
+
+// Bindings are the terms that wildcards matched, keyed by name
+type Bindings map[string]Term
+
+// Apply replaces the wildcards in a term with the terms they are bound to, such as to build the result of a rewrite
+func (b Bindings) Apply(term Term) Term {
+	return Map(term, func(t Term) Term {
+		if w, ok := t.(Wild); ok {
+			if r, ok := b[w.Name]; ok {
+				return r
+			}
+		}
+
+		return t
+	})
+}
+
+// Match reports whether a term has the shape of a pattern, and what each of the pattern's wildcards matched.
+// Sums and products match with their terms in any order, and nested ones are flattened, so _a + _b matches 1 + 2 + x.
+// When a sum or product only has wildcards left to match, the last one takes all of the remaining terms.
+// Sx{s} matches as s * x, and TP and PT as TPT, but wildcards never match missing terms, so #a * x doesn't match x.
+func Match(pattern, term Term) (Bindings, bool) {
+	var out Bindings
+
+	ok := match(pattern, term, Bindings{}, func(b Bindings) bool {
+		out = b
+		return true
+	})
+
+	return out, ok
+}
+
+// match tries each way a term can match a pattern, calling then with the bindings until it returns true
+func match(pattern, term Term, b Bindings, then func(Bindings) bool) bool {
+	if w, ok := pattern.(Wild); ok {
+		return bind(w, term, b, then)
+	}
+
+	pattern, term = view(pattern), view(term)
+
+	if op, ok := assoc(pattern); ok {
+		if top, ok := assoc(term); ok && top == op {
+			return matchOperands(operands(pattern, op), operands(term, op), op, b, then)
+		}
+
+		return false
+	}
+
+	if shape(pattern) != shape(term) {
+		return false
+	}
+
+	return matchChildren(Children(pattern), Children(term), b, then)
+}
+
+// matchChildren matches terms against patterns in order
+func matchChildren(patterns, terms []Term, b Bindings, then func(Bindings) bool) bool {
+	if len(patterns) == 0 {
+		return then(b)
+	}
+
+	return match(patterns[0], terms[0], b, func(b Bindings) bool {
+		return matchChildren(patterns[1:], terms[1:], b, then)
+	})
+}
+
+// matchOperands matches the operands of a sum or product in any order
+func matchOperands(patterns, terms []Term, op TokenID, b Bindings, then func(Bindings) bool) bool {
+	if len(patterns) == 0 {
+		return len(terms) == 0 && then(b)
+	}
+
+	// Wildcards are matched last, so that they have as little left to choose from as possible
+	i := 0
+	for j, p := range patterns {
+		if _, ok := p.(Wild); !ok {
+			i = j
+			break
+		}
+	}
+
+	p, rest := patterns[i], without(patterns, i)
+
+	if w, ok := p.(Wild); ok && len(rest) == 0 {
+		if len(terms) == 0 {
+			return false
+		}
+
+		return bind(w, join(terms, op), b, then)
+	}
+
+	for j, t := range terms {
+		left := without(terms, j)
+
+		if match(p, t, b, func(b Bindings) bool {
+			return matchOperands(rest, left, op, b, then)
+		}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bind matches a wildcard to a term, checking it against anything the wildcard already matched
+func bind(w Wild, term Term, b Bindings, then func(Bindings) bool) bool {
+	if !w.accepts(term) {
+		return false
+	}
+
+	if bound, ok := b[w.Name]; ok {
+		return Same(bound, term) && then(b)
+	}
+
+	next := make(Bindings, len(b)+1)
+	for k, v := range b {
+		next[k] = v
+	}
+	next[w.Name] = term
+
+	return then(next)
+}
+
+// view returns the form of a term used for matching, with powers by constants written as TPT
+func view(term Term) Term {
+	switch e := term.(type) {
+	case TP:
+		return TPT{e.X, S(e.P)}
+	case PT:
+		return TPT{S(e.V), e.X}
+	}
+
+	return term
+}
+
+// assoc returns TidAdd for sums and TidMul for products, whose terms can be matched in any order
+func assoc(term Term) (TokenID, bool) {
+	switch term.(type) {
+	case Add, Sum:
+		return TidAdd, true
+	case Mul, Prod, Sx:
+		return TidMul, true
+	}
+
+	return 0, false
+}
+
+// operands flattens nested sums or products into a list of their terms
+func operands(term Term, op TokenID) []Term {
+	if o, ok := assoc(term); !ok || o != op {
+		return []Term{term}
+	}
+
+	if e, ok := term.(Sx); ok {
+		return []Term{S(e.S), X{}}
+	}
+
+	out := make([]Term, 0)
+	for _, child := range Children(term) {
+		out = append(out, operands(child, op)...)
+	}
+
+	return out
+}
+
+// join makes the sum or product of a list of terms
+func join(terms []Term, op TokenID) Term {
+	if len(terms) == 1 {
+		return terms[0]
+	}
+
+	out := make([]Term, len(terms))
+	copy(out, terms)
+
+	if op == TidAdd {
+		return Sum(out)
+	}
+
+	return Prod(out)
+}
+
+// without returns a copy of a list of terms with one removed
+func without(terms []Term, i int) []Term {
+	out := make([]Term, 0, len(terms)-1)
+	out = append(out, terms[:i]...)
+	return append(out, terms[i+1:]...)
+}
@@ -0,0 +1,88 @@
+package alg
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/e74000/bimap"
+)
+
+// tidRegistered is the first token ID given out by Register, well clear of the IDs of this package's own terms
+const tidRegistered TokenID = 1 << 16
+
+// Bracketed is the arity of terms that take any number of arguments, ended by a closing token, like Sum and Prod
+const Bracketed = -1
+
+// Registration describes how a term type is tokenised and parsed.
+// Every term type in this package registers itself, and other packages can add their own with Register.
+type Registration struct {
+	// Name is the token's name when printing and tokenising strings.
+	// It is empty for tokens that are written differently, like scalars.
+	Name string
+
+	// Arity is the number of terms that follow the token in prefix notation, or Bracketed
+	Arity int
+
+	// Valued is true for tokens that carry a value, like the power of TP.
+	// The value is part of the token's structural key, and named tokens print it after their name as name:value.
+	Valued bool
+
+	// Close is the token that ends the arguments of a Bracketed term
+	Close TokenID
+
+	// New builds a term from the token's value and its arguments.
+	// Tokens without it, like closing brackets, can't start a term.
+	New func(val float64, args []Term) (Term, error)
+
+	// Type is a zero value of the term type, which is registered with gob and added to Terms
+	Type Term
+}
+
+var (
+	registry      = make(map[TokenID]Registration)
+	mTokenString  = make(map[TokenID]string)
+	bmTokenString = bimap.MapToBimap(mTokenString)
+)
+
+// Register adds a term type from outside this package, returning the token ID its Tokenise method should use.
+// It panics if the name is already taken, so it is best called when initialising a package level variable.
+func Register(r Registration) TokenID {
+	id := tidRegistered
+	for k := range registry {
+		if k >= id {
+			id = k + 1
+		}
+	}
+
+	register(id, r)
+
+	return id
+}
+
+// register adds a term type under a fixed token ID
+func register(id TokenID, r Registration) {
+	if _, ok := registry[id]; ok {
+		panic(fmt.Sprintf("alg: token %d is already registered", id))
+	}
+
+	if r.Name != "" {
+		if _, ok := lookup(r.Name); ok {
+			panic(fmt.Sprintf("alg: token name %q is already registered", r.Name))
+		}
+
+		mTokenString[id] = r.Name
+		bmTokenString = bimap.MapToBimap(mTokenString)
+	}
+
+	registry[id] = r
+
+	if r.Type != nil {
+		gob.Register(r.Type)
+		Terms = append(Terms, r.Type)
+	}
+}
+
+// lookup finds the token ID with a name
+func lookup(name string) (TokenID, bool) {
+	id := bmTokenString.GetRev(name)
+	return id, name != "" && mTokenString[id] == name
+}
@@ -0,0 +1,147 @@
+package alg
+
+import (
+	"math"
+)
+
+/*
+Roots defines the principal roots of a term:
+  Sqrt => The non negative square root of a term
+  Cbrt => The real cube root of a term, which is negative for negative terms unlike TP{x, 1.0/3}
+*/
+
+func init() {
+	register(TidSqrt, Registration{
+		Name:  "sqrt",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sqrt{X: args[0]}, nil
+		},
+		Type: Sqrt{},
+	})
+	register(TidCbrt, Registration{
+		Name:  "cbrt",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Cbrt{X: args[0]}, nil
+		},
+		Type: Cbrt{},
+	})
+}
+
+type Sqrt struct {
+	X Term
+}
+
+func (e Sqrt) Tokenise() Tokens {
+	t := Tokens{{id: TidSqrt}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Sqrt) E(x float64) float64 {
+	return math.Sqrt(e.X.E(x))
+}
+
+func (e Sqrt) Dx() Term {
+	return Div{
+		e.X.Dx(),
+		Prod{S(2), Sqrt{e.X}},
+	}.T()
+}
+
+func (e Sqrt) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Sqrt(val))
+	}
+
+	// sqrt(x^2) = |x|
+	switch inner := e.X.(type) {
+	case TP:
+		if inner.P == 2 {
+			return Abs{inner.X}.T()
+		}
+	case Mul:
+		if key(inner.A) == key(inner.B) {
+			return Abs{inner.A}.T()
+		}
+	}
+
+	return Sqrt{e.X}
+}
+
+func (e Sqrt) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Sqrt(val)
+}
+
+func (e Sqrt) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Sqrt) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Cbrt struct {
+	X Term
+}
+
+func (e Cbrt) Tokenise() Tokens {
+	t := Tokens{{id: TidCbrt}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Cbrt) E(x float64) float64 {
+	return math.Cbrt(e.X.E(x))
+}
+
+func (e Cbrt) Dx() Term {
+	return Div{
+		e.X.Dx(),
+		Prod{S(3), TP{Cbrt{e.X}, 2}},
+	}.T()
+}
+
+func (e Cbrt) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Cbrt(val))
+	}
+
+	// cbrt(x^3) = x, since the real cube root keeps the sign
+	if inner, ok := e.X.(TP); ok && inner.P == 3 {
+		return inner.X
+	}
+
+	return Cbrt{e.X}
+}
+
+func (e Cbrt) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Cbrt(val)
+}
+
+func (e Cbrt) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Cbrt) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
@@ -0,0 +1,699 @@
+package alg
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Equation is a pair of terms that are equal to each other
+type Equation struct {
+	L, R Term
+}
+
+// Solution is a family of solutions X = Value + k * Period for every integer k.
+// Period is zero when the solution is a single value.
+type Solution struct {
+	Value  float64
+	Period float64
+}
+
+const (
+	// solveLo and solveHi are the bounds Solve searches when it has to find roots numerically
+	solveLo, solveHi = -100, 100
+	// solveSamples is the number of points an interval is split into when searching for roots numerically
+	solveSamples = 20000
+)
+
+// Solve finds the values of X for which the two sides of an equation are equal.
+// Polynomials up to quartics are solved in closed form, and equations where X only appears once are solved by inverting each operation around it.
+// Anything else is solved numerically over [-100, 100], see SolveIn.
+func Solve(eq Equation) ([]Solution, error) {
+	if divZero(eq.L) || divZero(eq.R) {
+		return nil, errDivZero
+	}
+
+	f := Sub{eq.L, eq.R}.T()
+
+	if p, ok := poly(f); ok && len(p) <= 5 {
+		return polyRoots(p)
+	}
+
+	lx, rx := hasX(eq.L), hasX(eq.R)
+
+	if lx != rx {
+		term, other := eq.L, eq.R
+		if rx {
+			term, other = eq.R, eq.L
+		}
+
+		sols, err := isolate(term, []Solution{{Value: other.E(0)}})
+		if err == nil {
+			return check(eq, sols), nil
+		}
+	}
+
+	return SolveIn(eq, solveLo, solveHi)
+}
+
+// SolveIn numerically finds the solutions of an equation in [a, b], by looking for sign changes and refining them by bisection.
+// Solutions where the two sides touch without crossing are not found.
+func SolveIn(eq Equation, a, b float64) ([]Solution, error) {
+	if !(a < b) {
+		return nil, errors.New("invalid interval")
+	}
+
+	f := Sub{eq.L, eq.R}.T()
+	sols := make([]Solution, 0)
+
+	prevX, prevY := a, f.E(a)
+	for i := 1; i <= solveSamples; i++ {
+		x := a + (b-a)*float64(i)/solveSamples
+		y := f.E(x)
+
+		if prevY == 0 {
+			sols = append(sols, Solution{Value: prevX})
+		} else if prevY*y < 0 {
+			root := bisect(f, prevX, x, prevY)
+
+			// Sign changes across poles are not roots
+			if math.Abs(f.E(root)) < 1e-6*(1+math.Abs(prevY)+math.Abs(y)) {
+				sols = append(sols, Solution{Value: root})
+			}
+		}
+
+		prevX, prevY = x, y
+	}
+
+	if prevY == 0 {
+		sols = append(sols, Solution{Value: prevX})
+	}
+
+	return sols, nil
+}
+
+// bisect finds the root of f between a and b, given that f(a) = fa has the opposite sign to f(b)
+func bisect(f Term, a, b, fa float64) float64 {
+	for i := 0; i < 100 && a != b; i++ {
+		m := (a + b) / 2
+		if m == a || m == b {
+			break
+		}
+
+		fm := f.E(m)
+		if fm == 0 {
+			return m
+		}
+
+		if (fm < 0) == (fa < 0) {
+			a, fa = m, fm
+		} else {
+			b = m
+		}
+	}
+
+	return (a + b) / 2
+}
+
+// check removes solutions that do not satisfy the equation, such as roots introduced by squaring.
+func check(eq Equation, sols []Solution) []Solution {
+	out := make([]Solution, 0, len(sols))
+
+	for _, sol := range sols {
+		l, r := eq.L.E(sol.Value), eq.R.E(sol.Value)
+
+		if math.Abs(l-r) <= 1e-9*math.Max(1, math.Max(math.Abs(l), math.Abs(r))) {
+			out = append(out, sol)
+		}
+	}
+
+	return out
+}
+
+// hasX returns true if a term depends on X
+func hasX(term Term) bool {
+	for _, token := range term.Tokenise() {
+		if token.id == TidX || token.id == TidSx {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isolate inverts term = v for each v in rhs, peeling off operations until only X is left.
+func isolate(term Term, rhs []Solution) ([]Solution, error) {
+	switch e := term.(type) {
+	case X:
+		return rhs, nil
+	case Sx:
+		if e.S == 0 {
+			return nil, errors.New("cannot isolate 0x")
+		}
+		return mapLinear(rhs, 1/e.S, 0), nil
+	case Add:
+		return isolate(Sum{e.A, e.B}, rhs)
+	case Sub:
+		return isolate(Sum{e.A, Prod{S(-1), e.B}}, rhs)
+	case Sum:
+		inner, rest, err := pick(e)
+		if err != nil {
+			return nil, err
+		}
+		return isolate(inner, mapLinear(rhs, 1, -Sum(rest).E(0)))
+	case Mul:
+		return isolate(Prod{e.A, e.B}, rhs)
+	case Prod:
+		inner, rest, err := pick(e)
+		if err != nil {
+			return nil, err
+		}
+
+		c := Prod(rest).E(0)
+		if c == 0 {
+			return nil, errors.New("cannot isolate a term multiplied by 0")
+		}
+		return isolate(inner, mapLinear(rhs, 1/c, 0))
+	case Div:
+		if hasX(e.N) {
+			d := e.D.E(0)
+			if d == 0 {
+				return nil, errDivZero
+			}
+			return isolate(e.N, mapLinear(rhs, d, 0))
+		}
+		if periodic(rhs) {
+			return nil, errPeriodic
+		}
+		return isolate(e.D, mapEach(rhs, func(v float64) []Solution {
+			return []Solution{{Value: e.N.E(0) / v}}
+		}))
+	}
+
+	arg, sols, err := invert(term, rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	return isolate(arg, sols)
+}
+
+// pick splits the arguments of a sum or product into the single one that depends on X, and the rest.
+func pick(terms []Term) (Term, []Term, error) {
+	var inner Term
+	rest := make([]Term, 0, len(terms))
+
+	for _, term := range terms {
+		if !hasX(term) {
+			rest = append(rest, term)
+		} else if inner == nil {
+			inner = term
+		} else {
+			return nil, nil, errors.New("x appears more than once")
+		}
+	}
+
+	return inner, rest, nil
+}
+
+// mapLinear maps each solution v to v * m + c, which also maps the period of a family
+func mapLinear(rhs []Solution, m, c float64) []Solution {
+	out := make([]Solution, len(rhs))
+
+	for i, sol := range rhs {
+		out[i] = Solution{
+			Value:  sol.Value*m + c,
+			Period: math.Abs(sol.Period * m),
+		}
+	}
+
+	return out
+}
+
+// errPeriodic is returned when a periodic family would have to be passed through a non-linear function
+var errPeriodic = errors.New("cannot invert a periodic family of solutions")
+
+// errDivZero is returned for equations that divide by a constant 0, which have no solutions to find
+var errDivZero = errors.New("equation divides by 0")
+
+// divZero returns true if a term divides by a constant that is 0
+func divZero(term Term) bool {
+	_, found := Find(term, func(t Term) bool {
+		d, ok := t.(Div)
+		return ok && !hasX(d.D) && d.D.E(0) == 0
+	})
+
+	return found
+}
+
+// periodic returns true if any of the solutions is a periodic family
+func periodic(rhs []Solution) bool {
+	for _, sol := range rhs {
+		if sol.Period != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mapEach applies a non-linear inverse to each single solution
+func mapEach(rhs []Solution, inv func(v float64) []Solution) []Solution {
+	out := make([]Solution, 0, len(rhs))
+
+	for _, sol := range rhs {
+		for _, s := range inv(sol.Value) {
+			if !math.IsNaN(s.Value) && !math.IsInf(s.Value, 0) {
+				out = append(out, s)
+			}
+		}
+	}
+
+	return out
+}
+
+// invert undoes a single argument function, returning its argument and the values it must take.
+func invert(term Term, rhs []Solution) (Term, []Solution, error) {
+	if periodic(rhs) {
+		return nil, nil, errPeriodic
+	}
+
+	one := func(v float64) []Solution {
+		return []Solution{{Value: v}}
+	}
+
+	asin := func(v float64) []Solution {
+		a := math.Asin(v)
+		if a == math.Pi/2 || a == -math.Pi/2 {
+			return []Solution{{a, 2 * math.Pi}}
+		}
+		return []Solution{{a, 2 * math.Pi}, {math.Pi - a, 2 * math.Pi}}
+	}
+
+	acos := func(v float64) []Solution {
+		a := math.Acos(v)
+		if a == 0 || a == math.Pi {
+			return []Solution{{a, 2 * math.Pi}}
+		}
+		return []Solution{{a, 2 * math.Pi}, {-a, 2 * math.Pi}}
+	}
+
+	atan := func(v float64) []Solution {
+		return []Solution{{math.Atan(v), math.Pi}}
+	}
+
+	acosh := func(v float64) []Solution {
+		a := math.Acosh(v)
+		if a == 0 {
+			return one(a)
+		}
+		return []Solution{{Value: a}, {Value: -a}}
+	}
+
+	root := func(p float64) func(v float64) []Solution {
+		return func(v float64) []Solution {
+			if p == math.Trunc(p) && math.Mod(p, 2) != 0 {
+				return one(math.Copysign(math.Pow(math.Abs(v), 1/p), v))
+			}
+
+			r := math.Pow(v, 1/p)
+			if p == math.Trunc(p) && r != 0 {
+				return []Solution{{Value: r}, {Value: -r}}
+			}
+			return one(r)
+		}
+	}
+
+	log := func(base float64) func(v float64) []Solution {
+		return func(v float64) []Solution {
+			return one(math.Log(v) / math.Log(base))
+		}
+	}
+
+	recip := func(inv func(v float64) []Solution) func(v float64) []Solution {
+		return func(v float64) []Solution {
+			return inv(1 / v)
+		}
+	}
+
+	// forward undoes an inverse function, which only has solutions for values in its range [lo, hi]
+	forward := func(f func(float64) float64, lo, hi float64) func(v float64) []Solution {
+		return func(v float64) []Solution {
+			if v < lo || v > hi {
+				return nil
+			}
+			return one(f(v))
+		}
+	}
+
+	inf := math.Inf(1)
+
+	switch e := term.(type) {
+	case Exp:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Log(v)) }), nil
+	case Ln:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Exp(v)) }), nil
+	case Log:
+		if !hasX(e.Base) {
+			b := e.Base.E(0)
+			return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Pow(b, v)) }), nil
+		}
+	case Log2:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Exp2(v)) }), nil
+	case Log10:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Pow(10, v)) }), nil
+	case Sqrt:
+		return e.X, mapEach(rhs, func(v float64) []Solution {
+			if v < 0 {
+				return nil
+			}
+			return one(v * v)
+		}), nil
+	case Cbrt:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(v * v * v) }), nil
+	case Sin:
+		return e.X, mapEach(rhs, asin), nil
+	case Cos:
+		return e.X, mapEach(rhs, acos), nil
+	case Tan:
+		return e.X, mapEach(rhs, atan), nil
+	case Csc:
+		return e.X, mapEach(rhs, recip(asin)), nil
+	case Sec:
+		return e.X, mapEach(rhs, recip(acos)), nil
+	case Cot:
+		return e.X, mapEach(rhs, recip(atan)), nil
+	case Sinh:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Asinh(v)) }), nil
+	case Cosh:
+		return e.X, mapEach(rhs, acosh), nil
+	case Tanh:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Atanh(v)) }), nil
+	case Csch:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Asinh(1 / v)) }), nil
+	case Sech:
+		return e.X, mapEach(rhs, recip(acosh)), nil
+	case Coth:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Atanh(1 / v)) }), nil
+	case Asin:
+		return e.X, mapEach(rhs, forward(math.Sin, -math.Pi/2, math.Pi/2)), nil
+	case Acos:
+		return e.X, mapEach(rhs, forward(math.Cos, 0, math.Pi)), nil
+	case Atan:
+		return e.X, mapEach(rhs, forward(math.Tan, -math.Pi/2, math.Pi/2)), nil
+	case Asec:
+		return e.X, mapEach(rhs, forward(func(v float64) float64 { return 1 / math.Cos(v) }, 0, math.Pi)), nil
+	case Acsc:
+		return e.X, mapEach(rhs, forward(func(v float64) float64 { return 1 / math.Sin(v) }, -math.Pi/2, math.Pi/2)), nil
+	case Acot:
+		return e.X, mapEach(rhs, forward(func(v float64) float64 { return math.Tan(math.Pi/2 - v) }, 0, math.Pi)), nil
+	case Asinh:
+		return e.X, mapEach(rhs, forward(math.Sinh, -inf, inf)), nil
+	case Acosh:
+		return e.X, mapEach(rhs, forward(math.Cosh, 0, inf)), nil
+	case Atanh:
+		return e.X, mapEach(rhs, forward(math.Tanh, -inf, inf)), nil
+	case Asech:
+		return e.X, mapEach(rhs, forward(func(v float64) float64 { return 1 / math.Cosh(v) }, 0, inf)), nil
+	case Acsch:
+		return e.X, mapEach(rhs, forward(func(v float64) float64 { return 1 / math.Sinh(v) }, -inf, inf)), nil
+	case Acoth:
+		return e.X, mapEach(rhs, forward(func(v float64) float64 { return 1 / math.Tanh(v) }, -inf, inf)), nil
+	case Erf:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Erfinv(v)) }), nil
+	case Erfc:
+		return e.X, mapEach(rhs, func(v float64) []Solution { return one(math.Erfcinv(v)) }), nil
+	case Abs:
+		return e.X, mapEach(rhs, func(v float64) []Solution {
+			if v < 0 {
+				return nil
+			} else if v == 0 {
+				return one(0)
+			}
+			return []Solution{{Value: -v}, {Value: v}}
+		}), nil
+	case TP:
+		return e.X, mapEach(rhs, root(e.P)), nil
+	case PT:
+		return e.X, mapEach(rhs, log(e.V)), nil
+	case TPT:
+		if !hasX(e.A) {
+			return e.B, mapEach(rhs, log(e.A.E(0))), nil
+		} else if !hasX(e.B) {
+			return e.A, mapEach(rhs, root(e.B.E(0))), nil
+		}
+	}
+
+	return nil, nil, errors.New("cannot invert term")
+}
+
+// poly converts a term into the coefficients of a polynomial in X (lowest order first), returning false if it is not one.
+func poly(term Term) ([]float64, bool) {
+	if !hasX(term) {
+		// Constants that aren't finite, like 1/0, can't be coefficients
+		v := term.E(0)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+
+		return []float64{v}, true
+	}
+
+	switch e := term.(type) {
+	case X:
+		return []float64{0, 1}, true
+	case Sx:
+		return []float64{0, e.S}, true
+	case Add:
+		return poly(Sum{e.A, e.B})
+	case Sub:
+		return poly(Sum{e.A, Prod{S(-1), e.B}})
+	case Sum:
+		out := []float64{0}
+		for _, sub := range e {
+			p, ok := poly(sub)
+			if !ok {
+				return nil, false
+			}
+
+			for len(out) < len(p) {
+				out = append(out, 0)
+			}
+			for i, v := range p {
+				out[i] += v
+			}
+		}
+		return out, true
+	case Mul:
+		return poly(Prod{e.A, e.B})
+	case Prod:
+		out := []float64{1}
+		for _, sub := range e {
+			p, ok := poly(sub)
+			if !ok {
+				return nil, false
+			}
+			out = polyMul(out, p)
+		}
+		return out, true
+	case Div:
+		if hasX(e.D) {
+			return nil, false
+		}
+
+		p, ok := poly(e.N)
+		if !ok {
+			return nil, false
+		}
+
+		d := e.D.E(0)
+		if d == 0 {
+			return nil, false
+		}
+
+		for i := range p {
+			p[i] /= d
+		}
+		return p, true
+	case TP:
+		if e.P < 0 || e.P != math.Trunc(e.P) || e.P > 16 {
+			return nil, false
+		}
+
+		p, ok := poly(e.X)
+		if !ok {
+			return nil, false
+		}
+
+		out := []float64{1}
+		for i := 0; i < int(e.P); i++ {
+			out = polyMul(out, p)
+		}
+		return out, true
+	case TPT:
+		if hasX(e.B) {
+			return nil, false
+		}
+		return poly(TP{e.A, e.B.E(0)})
+	}
+
+	return nil, false
+}
+
+// polyMul multiplies two polynomials
+func polyMul(a, b []float64) []float64 {
+	out := make([]float64, len(a)+len(b)-1)
+
+	for i, av := range a {
+		for j, bv := range b {
+			out[i+j] += av * bv
+		}
+	}
+
+	return out
+}
+
+// polyRoots finds the real roots of a polynomial of degree at most 4 in closed form.
+func polyRoots(p []float64) ([]Solution, error) {
+	for len(p) > 1 && p[len(p)-1] == 0 {
+		p = p[:len(p)-1]
+	}
+
+	var roots []float64
+
+	switch len(p) {
+	case 1:
+		if p[0] == 0 {
+			return nil, errors.New("equation holds for every x")
+		}
+		return []Solution{}, nil
+	case 2:
+		roots = []float64{-p[0] / p[1]}
+	case 3:
+		roots = quadratic(p[2], p[1], p[0])
+	case 4:
+		roots = cubic(p[3], p[2], p[1], p[0])
+	case 5:
+		roots = quartic(p[4], p[3], p[2], p[1], p[0])
+	default:
+		return nil, errors.New("polynomial degree is too high to solve in closed form")
+	}
+
+	// Polish each root with a few newton steps, since the closed forms lose precision
+	for i, r := range roots {
+		for k := 0; k < 3; k++ {
+			var f, df float64
+			for j := len(p) - 1; j >= 0; j-- {
+				df = df*r + f
+				f = f*r + p[j]
+			}
+
+			if df == 0 {
+				break
+			}
+			r -= f / df
+		}
+
+		roots[i] = r
+	}
+
+	sort.Float64s(roots)
+
+	sols := make([]Solution, 0, len(roots))
+	for i, r := range roots {
+		if i > 0 && math.Abs(r-roots[i-1]) <= 1e-9*math.Max(1, math.Abs(r)) {
+			continue
+		}
+		sols = append(sols, Solution{Value: r})
+	}
+
+	return sols, nil
+}
+
+// quadratic returns the real roots of a x^2 + b x + c
+func quadratic(a, b, c float64) []float64 {
+	disc := b*b - 4*a*c
+
+	if disc < 0 {
+		return nil
+	} else if disc == 0 {
+		return []float64{-b / (2 * a)}
+	}
+
+	// Avoid cancellation by computing the larger root first
+	q := -(b + math.Copysign(math.Sqrt(disc), b)) / 2
+	if q == 0 {
+		return []float64{0, 0}
+	}
+
+	return []float64{q / a, c / q}
+}
+
+// cubic returns the real roots of a x^3 + b x^2 + c x + d
+func cubic(a, b, c, d float64) []float64 {
+	b, c, d = b/a, c/a, d/a
+
+	// Substitute x = t - b/3 to get t^3 + p t + q
+	p := c - b*b/3
+	q := 2*b*b*b/27 - b*c/3 + d
+	shift := -b / 3
+
+	disc := q*q/4 + p*p*p/27
+
+	if disc > 0 {
+		s := math.Sqrt(disc)
+		return []float64{math.Cbrt(-q/2+s) + math.Cbrt(-q/2-s) + shift}
+	}
+
+	if p == 0 {
+		return []float64{shift}
+	}
+
+	// Three real roots, use the trigonometric method
+	r := 2 * math.Sqrt(-p/3)
+	phi := math.Acos(math.Max(-1, math.Min(1, 3*q/(p*r))))
+
+	return []float64{
+		r*math.Cos(phi/3) + shift,
+		r*math.Cos((phi+2*math.Pi)/3) + shift,
+		r*math.Cos((phi+4*math.Pi)/3) + shift,
+	}
+}
+
+// quartic returns the real roots of a x^4 + b x^3 + c x^2 + d x + e using ferrari's method
+func quartic(a, b, c, d, e float64) []float64 {
+	b, c, d, e = b/a, c/a, d/a, e/a
+
+	// Substitute x = y - b/4 to get y^4 + p y^2 + q y + r
+	p := c - 3*b*b/8
+	q := d - b*c/2 + b*b*b/8
+	r := e - b*d/4 + b*b*c/16 - 3*b*b*b*b/256
+	shift := -b / 4
+
+	var ys []float64
+
+	if math.Abs(q) < 1e-14 {
+		for _, z := range quadratic(1, p, r) {
+			if z >= 0 {
+				ys = append(ys, math.Sqrt(z), -math.Sqrt(z))
+			}
+		}
+	} else {
+		// The resolvent cubic always has a positive root m, since it is -q^2 at 0
+		var m float64
+		for _, root := range cubic(8, 8*p, 2*p*p-8*r, -q*q) {
+			m = math.Max(m, root)
+		}
+
+		s := math.Sqrt(2 * m)
+		ys = append(ys, quadratic(1, s, p/2+m-q/(2*s))...)
+		ys = append(ys, quadratic(1, -s, p/2+m+q/(2*s))...)
+	}
+
+	out := make([]float64, len(ys))
+	for i, y := range ys {
+		out[i] = y + shift
+	}
+
+	return out
+}
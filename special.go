@@ -0,0 +1,805 @@
+package alg
+
+import (
+	"errors"
+	"math"
+)
+
+/*
+Special defines special functions used in statistics and physics:
+  Erf       => The error function
+  Erfc      => The complementary error function, 1 - erf(x)
+  Gamma     => The gamma function, with poles at 0, -1, -2, ...
+  LogGamma  => The log of the absolute value of the gamma function
+  Digamma   => The derivative of LogGamma
+  Polygamma => The Nth derivative of Digamma
+  Beta      => The beta function, Gamma(A) Gamma(B) / Gamma(A + B)
+  BesselJ   => Bessel function of the first kind of integer order N
+  BesselY   => Bessel function of the second kind of integer order N
+The integer orders are tokenised as a scalar before the argument, like the power of TP.
+T knows the values that have exact forms, such as gamma at positive integers and half integers, where it is a factorial or a multiple of sqrt(pi).
+*/
+
+func init() {
+	register(TidErf, Registration{
+		Name:  "erf",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Erf{X: args[0]}, nil
+		},
+		Type: Erf{},
+	})
+	register(TidErfc, Registration{
+		Name:  "erfc",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Erfc{X: args[0]}, nil
+		},
+		Type: Erfc{},
+	})
+	register(TidGamma, Registration{
+		Name:  "gamma",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Gamma{X: args[0]}, nil
+		},
+		Type: Gamma{},
+	})
+	register(TidLogGamma, Registration{
+		Name:  "lgamma",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return LogGamma{X: args[0]}, nil
+		},
+		Type: LogGamma{},
+	})
+	register(TidDigamma, Registration{
+		Name:  "digamma",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Digamma{X: args[0]}, nil
+		},
+		Type: Digamma{},
+	})
+	register(TidPolygamma, Registration{
+		Name:  "polygamma",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			n, err := order(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return Polygamma{N: n, X: args[1]}, nil
+		},
+		Type: Polygamma{},
+	})
+	register(TidBeta, Registration{
+		Name:  "beta",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Beta{A: args[0], B: args[1]}, nil
+		},
+		Type: Beta{},
+	})
+	register(TidBesselJ, Registration{
+		Name:  "besselj",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			n, err := order(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return BesselJ{N: n, X: args[1]}, nil
+		},
+		Type: BesselJ{},
+	})
+	register(TidBesselY, Registration{
+		Name:  "bessely",
+		Arity: 2,
+		New: func(_ float64, args []Term) (Term, error) {
+			n, err := order(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return BesselY{N: n, X: args[1]}, nil
+		},
+		Type: BesselY{},
+	})
+}
+
+type Erf struct {
+	X Term
+}
+
+func (e Erf) Tokenise() Tokens {
+	t := Tokens{{id: TidErf}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Erf) E(x float64) float64 {
+	return math.Erf(e.X.E(x))
+}
+
+func (e Erf) Dx() Term {
+	return Prod{
+		S(2 / math.SqrtPi),
+		e.X.Dx(),
+		Exp{Prod{S(-1), TP{e.X, 2}}},
+	}.T()
+}
+
+func (e Erf) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Erf(val))
+	}
+
+	return Erf{e.X}
+}
+
+func (e Erf) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Erf(val)
+}
+
+func (e Erf) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Erf) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Erfc struct {
+	X Term
+}
+
+func (e Erfc) Tokenise() Tokens {
+	t := Tokens{{id: TidErfc}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Erfc) E(x float64) float64 {
+	return math.Erfc(e.X.E(x))
+}
+
+func (e Erfc) Dx() Term {
+	return Prod{
+		S(-2 / math.SqrtPi),
+		e.X.Dx(),
+		Exp{Prod{S(-1), TP{e.X, 2}}},
+	}.T()
+}
+
+func (e Erfc) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Erfc(val))
+	}
+
+	return Erfc{e.X}
+}
+
+func (e Erfc) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Erfc(val)
+}
+
+func (e Erfc) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Erfc) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Gamma struct {
+	X Term
+}
+
+func (e Gamma) Tokenise() Tokens {
+	t := Tokens{{id: TidGamma}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Gamma) E(x float64) float64 {
+	return gamma(e.X.E(x))
+}
+
+func (e Gamma) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		Gamma{e.X},
+		Digamma{e.X},
+	}.T()
+}
+
+func (e Gamma) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		if exact, ok := gammaExact(val); ok {
+			return exact
+		}
+
+		return S(gamma(val))
+	}
+
+	return Gamma{e.X}
+}
+
+func (e Gamma) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, gamma(val)
+}
+
+func (e Gamma) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Gamma) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type LogGamma struct {
+	X Term
+}
+
+func (e LogGamma) Tokenise() Tokens {
+	t := Tokens{{id: TidLogGamma}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e LogGamma) E(x float64) float64 {
+	return lgamma(e.X.E(x))
+}
+
+func (e LogGamma) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		Digamma{e.X},
+	}.T()
+}
+
+func (e LogGamma) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		// Keep pi symbolic at half integers, such as log gamma(1/2) = ln(sqrt(pi))
+		if exact, ok := gammaExact(val); ok {
+			if _, number := exact.(S); !number {
+				return Ln{exact}.T()
+			}
+		}
+
+		return S(lgamma(val))
+	}
+
+	return LogGamma{e.X}
+}
+
+func (e LogGamma) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, lgamma(val)
+}
+
+func (e LogGamma) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e LogGamma) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Digamma struct {
+	X Term
+}
+
+func (e Digamma) Tokenise() Tokens {
+	t := Tokens{{id: TidDigamma}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Digamma) E(x float64) float64 {
+	return polygamma(0, e.X.E(x))
+}
+
+func (e Digamma) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		Polygamma{1, e.X},
+	}.T()
+}
+
+func (e Digamma) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(polygamma(0, val))
+	}
+
+	return Digamma{e.X}
+}
+
+func (e Digamma) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, polygamma(0, val)
+}
+
+func (e Digamma) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Digamma) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Polygamma struct {
+	N int
+	X Term
+}
+
+func (e Polygamma) Tokenise() Tokens {
+	t := Tokens{{id: TidPolygamma}, {id: TidS, val: float64(e.N)}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e Polygamma) E(x float64) float64 {
+	return polygamma(e.N, e.X.E(x))
+}
+
+func (e Polygamma) Dx() Term {
+	return Prod{
+		e.X.Dx(),
+		Polygamma{e.N + 1, e.X},
+	}.T()
+}
+
+func (e Polygamma) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(polygamma(e.N, val))
+	}
+
+	if e.N == 0 {
+		return Digamma{e.X}
+	}
+
+	return Polygamma{e.N, e.X}
+}
+
+func (e Polygamma) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, polygamma(e.N, val)
+}
+
+func (e Polygamma) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Polygamma) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type Beta struct {
+	A, B Term
+}
+
+func (e Beta) Tokenise() Tokens {
+	t := Tokens{{id: TidBeta}}
+	t = append(t, e.A.Tokenise()...)
+	t = append(t, e.B.Tokenise()...)
+	return t
+}
+
+func (e Beta) E(x float64) float64 {
+	return beta(e.A.E(x), e.B.E(x))
+}
+
+func (e Beta) Dx() Term {
+	// d/dx B(a, b) = B(a, b) (a' (digamma(a) - digamma(a + b)) + b' (digamma(b) - digamma(a + b)))
+	sum := Digamma{Add{e.A, e.B}}
+
+	return Prod{
+		e,
+		Add{
+			Mul{e.A.Dx(), Sub{Digamma{e.A}, sum}},
+			Mul{e.B.Dx(), Sub{Digamma{e.B}, sum}},
+		},
+	}.T()
+}
+
+func (e Beta) T() Term {
+	e = simplified(e)
+
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if aok && bok {
+		// At two positive half integers, gamma(a) gamma(b) / gamma(a + b) is a multiple of pi
+		if c, ok := halfGamma(av); ok {
+			if d, ok := halfGamma(bv); ok {
+				if coef := c * d / factorial(int(av+bv)-1); coef != 1 {
+					return Mul{S(coef), Pi{}}
+				}
+				return Pi{}
+			}
+		}
+
+		return S(beta(av, bv))
+	}
+
+	// B(a, 1) = 1 / a, and beta is symmetric
+	if bok && bv == 1 {
+		return Div{S(1), e.A}.T()
+	} else if aok && av == 1 {
+		return Div{S(1), e.B}.T()
+	}
+
+	return Beta{e.A, e.B}
+}
+
+func (e Beta) Is() (bool, float64) {
+	aok, av := e.A.Is()
+	bok, bv := e.B.Is()
+
+	if !aok || !bok {
+		return false, 0
+	}
+
+	return true, beta(av, bv)
+}
+
+func (e Beta) Children() []Term {
+	return []Term{e.A, e.B}
+}
+
+func (e Beta) WithChildren(children []Term) Term {
+	e.A, e.B = children[0], children[1]
+	return e
+}
+
+type BesselJ struct {
+	N int
+	X Term
+}
+
+func (e BesselJ) Tokenise() Tokens {
+	t := Tokens{{id: TidBesselJ}, {id: TidS, val: float64(e.N)}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e BesselJ) E(x float64) float64 {
+	return math.Jn(e.N, e.X.E(x))
+}
+
+func (e BesselJ) Dx() Term {
+	return Prod{
+		S(0.5),
+		e.X.Dx(),
+		Sub{
+			BesselJ{e.N - 1, e.X},
+			BesselJ{e.N + 1, e.X},
+		},
+	}.T()
+}
+
+func (e BesselJ) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		// J_0(0) = 1, and every other order is 0 there
+		if val == 0 {
+			if e.N == 0 {
+				return S(1)
+			}
+			return S(0)
+		}
+
+		return S(math.Jn(e.N, val))
+	}
+
+	// J_-n = (-1)^n J_n
+	if e.N < 0 {
+		return flipOrder(BesselJ{-e.N, e.X}, e.N)
+	}
+
+	return BesselJ{e.N, e.X}
+}
+
+func (e BesselJ) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Jn(e.N, val)
+}
+
+func (e BesselJ) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e BesselJ) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+type BesselY struct {
+	N int
+	X Term
+}
+
+func (e BesselY) Tokenise() Tokens {
+	t := Tokens{{id: TidBesselY}, {id: TidS, val: float64(e.N)}}
+	t = append(t, e.X.Tokenise()...)
+	return t
+}
+
+func (e BesselY) E(x float64) float64 {
+	return math.Yn(e.N, e.X.E(x))
+}
+
+func (e BesselY) Dx() Term {
+	return Prod{
+		S(0.5),
+		e.X.Dx(),
+		Sub{
+			BesselY{e.N - 1, e.X},
+			BesselY{e.N + 1, e.X},
+		},
+	}.T()
+}
+
+func (e BesselY) T() Term {
+	e = simplified(e)
+
+	ok, val := e.X.Is()
+	if ok {
+		return S(math.Yn(e.N, val))
+	}
+
+	// Y_-n = (-1)^n Y_n
+	if e.N < 0 {
+		return flipOrder(BesselY{-e.N, e.X}, e.N)
+	}
+
+	return BesselY{e.N, e.X}
+}
+
+func (e BesselY) Is() (bool, float64) {
+	ok, val := e.X.Is()
+	if !ok {
+		return false, 0
+	}
+
+	return true, math.Yn(e.N, val)
+}
+
+func (e BesselY) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e BesselY) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
+// order reads the integer order of a special function from a parsed term
+func order(term Term) (int, error) {
+	ok, v := term.Is()
+	if !ok || v != math.Trunc(v) || v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, errors.New("order must be an integer constant")
+	}
+
+	return int(v), nil
+}
+
+// flipOrder returns (-1)^n times a bessel function, for rewriting negative orders
+func flipOrder(term Term, n int) Term {
+	if n%2 == 0 {
+		return term
+	}
+
+	return Mul{S(-1), term}
+}
+
+// gammaExact returns gamma(v) as an exact term where it has one.
+// At positive integers it is a factorial, kept to the ones that fit exactly in a float, and at positive half integers a multiple of sqrt(pi).
+func gammaExact(v float64) (Term, bool) {
+	if v >= 1 && v <= 18 && v == math.Trunc(v) {
+		return S(factorial(int(v) - 1)), true
+	}
+
+	c, ok := halfGamma(v)
+	if !ok {
+		return nil, false
+	} else if c == 1 {
+		return Sqrt{Pi{}}, true
+	}
+
+	return Mul{S(c), Sqrt{Pi{}}}, true
+}
+
+// halfGamma returns gamma(v) / sqrt(pi) for the positive half integers up to 10.5, where it is (2n)! / (4^n n!) with n = v - 1/2.
+// The result is an odd number over a power of 2, so it is exact.
+func halfGamma(v float64) (float64, bool) {
+	n := v - 0.5
+	if n < 0 || n > 10 || n != math.Trunc(n) {
+		return 0, false
+	}
+
+	c := 1.0
+	for k := 1; k <= int(n); k++ {
+		c *= float64(2*k-1) / 2
+	}
+
+	return c, true
+}
+
+// isPole returns true at the poles of the gamma function, 0, -1, -2, ...
+func isPole(v float64) bool {
+	return v <= 0 && v == math.Trunc(v)
+}
+
+// gamma is math.Gamma, but NaN rather than infinite at the poles, since the sign of the infinity depends on the side
+func gamma(v float64) float64 {
+	if isPole(v) {
+		return math.NaN()
+	}
+
+	return math.Gamma(v)
+}
+
+// lgamma returns log |gamma(v)|
+func lgamma(v float64) float64 {
+	if isPole(v) {
+		return math.NaN()
+	}
+
+	l, _ := math.Lgamma(v)
+	return l
+}
+
+// beta returns gamma(a) gamma(b) / gamma(a + b), working with logs so that it doesn't overflow for large arguments
+func beta(a, b float64) float64 {
+	if isPole(a) || isPole(b) {
+		return math.NaN()
+	}
+
+	la, sa := math.Lgamma(a)
+	lb, sb := math.Lgamma(b)
+	if isPole(a + b) {
+		return 0
+	}
+
+	lab, sab := math.Lgamma(a + b)
+	return float64(sa*sb*sab) * math.Exp(la+lb-lab)
+}
+
+// polygamma returns the nth derivative of the digamma function.
+// The argument is shifted up with the recurrence psi_n(x) = psi_n(x + 1) - (-1)^n n! / x^(n+1), until the asymptotic series is accurate.
+func polygamma(n int, v float64) float64 {
+	if n < 0 || math.IsNaN(v) || isPole(v) {
+		return math.NaN()
+	}
+
+	if math.IsInf(v, 1) {
+		if n == 0 {
+			return v
+		}
+		return 0
+	}
+
+	// Use the reflection formula for digamma far below zero, where shifting up would take too long
+	if n == 0 && v < -100 {
+		return polygamma(0, 1-v) - math.Pi/math.Tan(math.Pi*v)
+	} else if v < -1e6 {
+		return math.NaN()
+	}
+
+	fact := 1.0
+	for i := 2; i <= n; i++ {
+		fact *= float64(i)
+	}
+
+	// sign is (-1)^(n+1)
+	sign := -1.0
+	if n%2 == 1 {
+		sign = 1
+	}
+
+	var shift float64
+	limit := 20 + float64(n)
+	for v < limit {
+		if n == 0 {
+			shift -= 1 / v
+		} else {
+			shift += sign * fact / math.Pow(v, float64(n+1))
+		}
+		v++
+	}
+
+	// bernoulli holds B_2k / (2k)!, for the asymptotic series
+	bernoulli := []float64{
+		1.0 / 6 / 2,
+		-1.0 / 30 / 24,
+		1.0 / 42 / 720,
+		-1.0 / 30 / 40320,
+		5.0 / 66 / 3628800,
+		-691.0 / 2730 / 479001600,
+		7.0 / 6 / 87178291200,
+	}
+
+	if n == 0 {
+		out := math.Log(v) - 1/(2*v)
+		for k, b := range bernoulli {
+			out -= b * factorial(2*k+1) / math.Pow(v, float64(2*k+2))
+		}
+		return out + shift
+	}
+
+	out := factorial(n-1)/math.Pow(v, float64(n)) + fact/(2*math.Pow(v, float64(n+1)))
+	for k, b := range bernoulli {
+		out += b * factorial(2*k+2+n-1) / math.Pow(v, float64(2*k+2+n))
+	}
+
+	return sign*out + shift
+}
+
+// factorial returns n!
+func factorial(n int) float64 {
+	out := 1.0
+	for i := 2; i <= n; i++ {
+		out *= float64(i)
+	}
+
+	return out
+}
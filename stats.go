@@ -0,0 +1,103 @@
+package alg
+
+import (
+	"reflect"
+)
+
+// Costs are rough weights for evaluating each type of term once, in about the time of an addition, keyed by type name.
+// Types that aren't listed cost 1, and it can be changed or added to for terms from outside this package.
+var Costs = map[string]float64{
+	"S": 0, "X": 0, "Pi": 0, "EulerE": 0, "Phi": 0,
+	"Sx": 1, "Add": 1, "Sub": 1, "Mul": 1, "Sum": 1, "Prod": 1,
+	"Div": 4, "TP": 4, "TPT": 40, "PT": 40,
+	"Exp": 20, "Ln": 20, "Log": 40, "Log2": 20, "Log10": 20,
+	"Sqrt": 8, "Cbrt": 20,
+	"Sin": 20, "Cos": 20, "Tan": 25, "Sec": 25, "Csc": 25, "Cot": 25,
+	"Asin": 30, "Acos": 30, "Atan": 30, "Asec": 30, "Acsc": 30, "Acot": 30, "Atan2": 35,
+	"Sinh": 25, "Cosh": 25, "Tanh": 25, "Sech": 25, "Csch": 25, "Coth": 25,
+	"Asinh": 30, "Acosh": 30, "Atanh": 30, "Asech": 30, "Acsch": 30, "Acoth": 30,
+	"Abs": 1, "Sign": 1, "Min": 1, "Max": 1, "Floor": 2, "Ceil": 2, "Mod": 10,
+	"Greater": 2, "Less": 2, "GreaterEqual": 2, "LessEqual": 2, "Equal": 2, "NotEqual": 2, "Range": 3,
+	"Erf": 40, "Erfc": 40, "Gamma": 60, "LogGamma": 60, "Digamma": 80, "Polygamma": 150, "Beta": 150,
+	"BesselJ": 150, "BesselY": 150,
+	"Call": 1,
+}
+
+// TermStats describes the size and shape of a term
+type TermStats struct {
+	// Nodes is the number of terms in the tree, and Depth is the length of the longest path from the root to a leaf
+	Nodes, Depth int
+
+	// Ops counts the terms of each type, keyed by type name
+	Ops map[string]int
+
+	// Cost is the estimated cost of evaluating the term once, using Costs.
+	// Sums and products cost one less than their number of terms, and calls also cost their function's body.
+	Cost float64
+}
+
+// Stats measures a term
+func Stats(term Term) TermStats {
+	s := TermStats{Ops: make(map[string]int)}
+
+	var visit func(t Term, depth int)
+	visit = func(t Term, depth int) {
+		name := reflect.TypeOf(t).Name()
+
+		s.Nodes++
+		s.Ops[name]++
+		if depth > s.Depth {
+			s.Depth = depth
+		}
+
+		s.Cost += cost(t, name)
+
+		for _, child := range Children(t) {
+			visit(child, depth+1)
+		}
+	}
+
+	visit(term, 1)
+
+	return s
+}
+
+// cost returns the cost of a term itself, not counting its children
+func cost(term Term, name string) float64 {
+	c, ok := Costs[name]
+	if !ok {
+		c = 1
+	}
+
+	switch e := term.(type) {
+	case Sum:
+		c *= joins(len(e))
+	case Prod:
+		c *= joins(len(e))
+	case Call:
+		if f, ok := lookupFunction(e.Name); ok {
+			c += Stats(f.Body).Cost
+		}
+	}
+
+	return c
+}
+
+// joins returns the number of operations needed to combine n terms
+func joins(n int) float64 {
+	if n < 2 {
+		return 0
+	}
+
+	return float64(n - 1)
+}
+
+// Cheaper returns whichever of two equivalent terms costs less to evaluate, preferring a when they cost the same.
+// It can be used to check that a rewrite, like T, actually made a term better.
+func Cheaper(a, b Term) Term {
+	if Stats(b).Cost < Stats(a).Cost {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,49 @@
+package alg
+
+// Substitute replaces X with another term everywhere in a term, turning Sx{s} into Mul{S(s), replacement}.
+// The result isn't simplified, so call T on it if needed.
+// Calls are substituted in their argument, since the body of a function is in terms of its own X.
+func Substitute(term, replacement Term) Term {
+	return Map(term, func(t Term) Term {
+		switch e := t.(type) {
+		case X:
+			return replacement
+		case Sx:
+			return Mul{S(e.S), replacement}
+		}
+
+		return t
+	})
+}
+
+// Compose returns f(g(x)), which is f with g substituted for X
+func Compose(f, g Term) Term {
+	return Substitute(f, g)
+}
+
+// SubstituteSubtree replaces every part of a term that is structurally equal to pattern with replacement.
+// Matches are found from the top down, so a match is never searched inside, and replacements can't create new matches.
+func SubstituteSubtree(term, pattern, replacement Term) Term {
+	k := key(pattern)
+
+	var sub func(t Term) Term
+	sub = func(t Term) Term {
+		if key(t) == k {
+			return replacement
+		}
+
+		p, ok := t.(Parent)
+		if !ok {
+			return t
+		}
+
+		children := p.Children()
+		for i, child := range children {
+			children[i] = sub(child)
+		}
+
+		return p.WithChildren(children)
+	}
+
+	return sub(term)
+}
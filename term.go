@@ -15,39 +15,5 @@ type Term interface {
 }
 
 // Terms is a list of all the different term types.
-// It is included to allow you to gob encode terms.
-var Terms = []Term{
-	new(S),
-	new(X),
-	new(Sx),
-	new(Exp),
-	new(Ln),
-	new(TPT),
-	new(TP),
-	new(PT),
-	new(Sum),
-	new(Prod),
-	new(Div),
-	new(Add),
-	new(Sub),
-	new(Mul),
-	new(Sin),
-	new(Cos),
-	new(Tan),
-	new(Sec),
-	new(Csc),
-	new(Cot),
-	new(Sinh),
-	new(Cosh),
-	new(Tanh),
-	new(Sech),
-	new(Csch),
-	new(Coth),
-	new(Greater),
-	new(Less),
-	new(GreaterEqual),
-	new(LessEqual),
-	new(Equal),
-	new(NotEqual),
-	new(Range),
-}
+// It is filled in as term types are registered, see Register, which also registers them with gob so that you can gob encode terms.
+var Terms []Term
@@ -3,7 +3,6 @@ package alg
 import (
 	"errors"
 	"fmt"
-	"github.com/e74000/bimap"
 	"regexp"
 	"strconv"
 	"strings"
@@ -47,48 +46,69 @@ const (
 	TidSumEn
 	TidProdSt
 	TidProdEn
+	TidAsin
+	TidAcos
+	TidAtan
+	TidAsec
+	TidAcsc
+	TidAcot
+	TidAtan2
+	TidAsinh
+	TidAcosh
+	TidAtanh
+	TidAsech
+	TidAcsch
+	TidAcoth
+	TidAbs
+	TidSign
+	TidMin
+	TidMax
+	TidFloor
+	TidCeil
+	TidMod
+	TidErf
+	TidErfc
+	TidGamma
+	TidLogGamma
+	TidDigamma
+	TidPolygamma
+	TidBeta
+	TidBesselJ
+	TidBesselY
+	TidPi
+	TidEulerE
+	TidPhi
+	TidLog
+	TidLog2
+	TidLog10
+	TidSqrt
+	TidCbrt
+	TidWild
+	TidCall
 )
 
 type Token struct {
 	id  TokenID
 	val float64
+
+	// name is the name of a wildcard or called function, which is kept in the token so that it doesn't depend on any global state
+	name string
+}
+
+// NewToken makes a token, for the Tokenise methods of registered terms
+func NewToken(id TokenID, val float64) Token {
+	return Token{id: id, val: val}
 }
 
-var mTokenString = map[TokenID]string{
-	TidX:            "x",
-	TidExp:          "e",
-	TidTPT:          "^",
-	TidLn:           "ln",
-	TidSin:          "sin",
-	TidCos:          "cos",
-	TidTan:          "tan",
-	TidSec:          "sec",
-	TidCsc:          "csc",
-	TidCot:          "cot",
-	TidSinh:         "sinh",
-	TidCosh:         "cosh",
-	TidTanh:         "tanh",
-	TidSech:         "sech",
-	TidCsch:         "csch",
-	TidCoth:         "coth",
-	TidAdd:          "+",
-	TidSub:          "-",
-	TidMul:          "*",
-	TidDiv:          "/",
-	TidGreater:      ">",
-	TidLess:         "<",
-	TidGreaterEqual: ">=",
-	TidLessEqual:    "<=",
-	TidEqual:        "==",
-	TidNotEqual:     "!=",
-	TidRange:        "<=>",
-	TidSumSt:        "+[",
-	TidSumEn:        "]+",
-	TidProdSt:       "*[",
-	TidProdEn:       "]*",
+// ID returns the token's ID
+func (t Token) ID() TokenID {
+	return t.id
 }
 
-var bmTokenString = bimap.MapToBimap(mTokenString)
+// Val returns the value a token carries, such as the value of a scalar
+func (t Token) Val() float64 {
+	return t.val
+}
 
 type Tokens []Token
 
@@ -106,6 +126,9 @@ func Tokenise(s string) (Tokens, error) {
 	for _, sub := range split {
 		if sub == "" {
 			continue
+		} else if isWild(sub) {
+			t = append(t, Token{id: TidWild, name: sub})
+			continue
 		} else if sub == "-" || sub == "." || sub == "x" {
 
 		} else if isScalar.MatchString(sub) {
@@ -129,7 +152,28 @@ func Tokenise(s string) (Tokens, error) {
 			continue
 		}
 
-		t = append(t, Token{id: bmTokenString.GetRev(sub)})
+		// Registered tokens with values are written name:value
+		if name, val, ok := strings.Cut(sub, ":"); ok {
+			id, ok := lookup(name)
+			if !ok || !registry[id].Valued {
+				return nil, fmt.Errorf("unknown token: %s", sub)
+			}
+
+			v, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			t = append(t, Token{id: id, val: v})
+			continue
+		}
+
+		id, ok := lookup(sub)
+		if !ok {
+			return nil, fmt.Errorf("unknown token: %s", sub)
+		}
+
+		t = append(t, Token{id: id})
 	}
 
 	return t, nil
@@ -143,8 +187,7 @@ func (t *Tokens) pop() Token {
 	return out
 }
 
-// Parse converts a token slice to a tree with prefix notation
-// This is a massive automatically-ish generated switch statement - I would rather if it could be avoided, but I can't figure out any better methods right now...
+// Parse converts a token slice to a tree with prefix notation, using the registration of each token to build its term
 func (t *Tokens) Parse() (Term, error) {
 	if len(*t) == 0 {
 		return nil, errors.New("tokens ended unexpectedly")
@@ -152,425 +195,66 @@ func (t *Tokens) Parse() (Term, error) {
 
 	temp := t.pop()
 
-	switch temp.id {
-	case TidS:
-		return S(temp.val), nil
-	case TidX:
-		return X{}, nil
-	case TidSx:
-		return Sx{temp.val}, nil
-	case TidExp:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Exp{
-			X: arg1,
-		}, nil
-	case TidLn:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Ln{
-			X: arg1,
-		}, nil
-	case TidTPT:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return TPT{
-			A: arg1,
-			B: arg2,
-		}, nil
-	case TidTP:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return TP{
-			X: arg1,
-			P: temp.val,
-		}, nil
-	case TidPT:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return PT{
-			V: temp.val,
-			X: arg1,
-		}, nil
-	case TidSin:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Sin{
-			X: arg1,
-		}, nil
-	case TidCos:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Cos{
-			X: arg1,
-		}, nil
-	case TidTan:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Tan{
-			X: arg1,
-		}, nil
-	case TidSec:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Sec{
-			X: arg1,
-		}, nil
-	case TidCsc:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Csc{
-			X: arg1,
-		}, nil
-	case TidCot:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Cot{
-			X: arg1,
-		}, nil
-	case TidCosh:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Cosh{
-			X: arg1,
-		}, nil
-	case TidSinh:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Sinh{
-			X: arg1,
-		}, nil
-	case TidTanh:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Tanh{
-			X: arg1,
-		}, nil
-	case TidSech:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Sech{
-			X: arg1,
-		}, nil
-	case TidCsch:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Csch{
-			X: arg1,
-		}, nil
-	case TidCoth:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Coth{
-			X: arg1,
-		}, nil
-	case TidAdd:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Add{
-			A: arg1,
-			B: arg2,
-		}, nil
-	case TidSub:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Sub{
-			A: arg1,
-			B: arg2,
-		}, nil
-	case TidMul:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Mul{
-			A: arg1,
-			B: arg2,
-		}, nil
-	case TidDiv:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Div{
-			N: arg1,
-			D: arg2,
-		}, nil
-	case TidGreater:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg3, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg4, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Greater{
-			A:    arg1,
-			B:    arg2,
-			If:   arg3,
-			Else: arg4,
-		}, nil
-	case TidLess:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg3, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg4, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Less{
-			A:    arg1,
-			B:    arg2,
-			If:   arg3,
-			Else: arg4,
-		}, nil
-	case TidGreaterEqual:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg3, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg4, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return GreaterEqual{
-			A:    arg1,
-			B:    arg2,
-			If:   arg3,
-			Else: arg4,
-		}, nil
-	case TidLessEqual:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg3, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg4, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return LessEqual{
-			A:    arg1,
-			B:    arg2,
-			If:   arg3,
-			Else: arg4,
-		}, nil
-	case TidEqual:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg3, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg4, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return Equal{
-			A:    arg1,
-			B:    arg2,
-			If:   arg3,
-			Else: arg4,
-		}, nil
-	case TidNotEqual:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg3, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg4, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		return NotEqual{
-			A:    arg1,
-			B:    arg2,
-			If:   arg3,
-			Else: arg4,
-		}, nil
-	case TidRange:
-		arg1, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg2, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg3, err := t.Parse()
-		if err != nil {
-			return nil, err
-		}
-		arg4, err := t.Parse()
-		if err != nil {
-			return nil, err
+	// Wildcards are named by their token, rather than built from a value
+	if temp.id == TidWild {
+		return Wild{temp.name}, nil
+	}
+
+	// Calls are too, and can only be parsed once their function is defined
+	if temp.id == TidCall {
+		if _, ok := lookupFunction(temp.name); !ok {
+			return nil, fmt.Errorf("function %q is not defined", temp.name)
 		}
-		arg5, err := t.Parse()
+
+		arg, err := t.Parse()
 		if err != nil {
 			return nil, err
 		}
-		return Range{
-			X:    arg1,
-			A:    arg2,
-			B:    arg3,
-			If:   arg4,
-			Else: arg5,
-		}, nil
-	case TidSumSt:
-		sum := make(Sum, 0)
-		closed := false
-		for len(*t) != 0 {
-			if (*t)[0].id == TidSumEn {
-				closed = true
-				t.pop()
-				break
-			}
 
-			arg, err := t.Parse()
-			if err != nil {
-				return nil, err
-			}
+		return Call{temp.name, arg}, nil
+	}
 
-			sum = append(sum, arg)
-		}
+	r, ok := registry[temp.id]
+	if !ok {
+		return nil, errors.New("failed to parse token")
+	} else if r.New == nil {
+		return nil, errors.New("unmatched brackets")
+	}
 
-		if !closed {
-			return nil, errors.New("unmatched brackets")
-		}
+	if r.Arity == Bracketed {
+		args := make([]Term, 0)
+		for {
+			if len(*t) == 0 {
+				return nil, errors.New("unmatched brackets")
+			}
 
-		return sum, nil
-	case TidProdSt:
-		prod := make(Prod, 0)
-		closed := false
-		for len(*t) != 0 {
-			if (*t)[0].id == TidProdEn {
-				closed = true
+			if (*t)[0].id == r.Close {
 				t.pop()
 				break
 			}
 
 			arg, err := t.Parse()
-			if arg != nil {
+			if err != nil {
 				return nil, err
 			}
 
-			prod = append(prod, arg)
+			args = append(args, arg)
 		}
 
-		if !closed {
-			return nil, errors.New("unmatched brackets")
+		return r.New(temp.val, args)
+	}
+
+	args := make([]Term, r.Arity)
+	for i := range args {
+		arg, err := t.Parse()
+		if err != nil {
+			return nil, err
 		}
 
-		return prod, nil
-	case TidSumEn, TidProdEn:
-		return nil, errors.New("unmatched brackets")
+		args[i] = arg
 	}
 
-	return nil, errors.New("failed to parse token")
+	return r.New(temp.val, args)
 }
 
 // String converts a token slice to a string
@@ -583,8 +267,14 @@ func (t *Tokens) String() string {
 			s += fmt.Sprintf("%.2f", token.val)
 		case TidSx:
 			s += fmt.Sprintf("%.2fx", token.val)
+		case TidWild, TidCall:
+			s += token.name
 		default:
 			s += bmTokenString.GetFor(token.id)
+
+			if registry[token.id].Valued {
+				s += ":" + strconv.FormatFloat(token.val, 'g', -1, 64)
+			}
 		}
 
 		s += " "
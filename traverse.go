@@ -0,0 +1,96 @@
+package alg
+
+// Parent is implemented by terms that have other terms inside them, which is every term in this package except S, X, Sx and the named constants.
+// Children are in field order, the same order as the paths of EvalError, and WithChildren takes the same number back, keeping any scalar fields.
+type Parent interface {
+	Term
+	Children() []Term
+	WithChildren(children []Term) Term
+}
+
+// Children returns the terms directly inside a term, or nil for terms that don't implement Parent
+func Children(term Term) []Term {
+	if p, ok := term.(Parent); ok {
+		return p.Children()
+	}
+
+	return nil
+}
+
+// simplified returns a term with each of its children simplified, for T methods to apply their rules to.
+// Checking the children with Is before simplifying them would miss children that only become constant once simplified, like sin(pi).
+func simplified[P Parent](e P) P {
+	children := e.Children()
+	for i, child := range children {
+		children[i] = child.T()
+	}
+
+	return e.WithChildren(children).(P)
+}
+
+// Walk visits every term in a tree, parents before their children.
+// The children of a term are skipped if visit returns false for it.
+func Walk(term Term, visit func(term Term) bool) {
+	if !visit(term) {
+		return
+	}
+
+	for _, child := range Children(term) {
+		Walk(child, visit)
+	}
+}
+
+// Map rebuilds a tree from the bottom up, replacing each term with f of the term with its children already mapped
+func Map(term Term, f func(term Term) Term) Term {
+	if p, ok := term.(Parent); ok {
+		children := p.Children()
+		for i, child := range children {
+			children[i] = Map(child, f)
+		}
+
+		term = p.WithChildren(children)
+	}
+
+	return f(term)
+}
+
+// Fold combines a tree from the bottom up, passing f each term along with what its children folded to.
+// For example, the depth of a tree is the largest depth of its children plus one.
+func Fold[A any](term Term, f func(term Term, children []A) A) A {
+	sub := Children(term)
+
+	folded := make([]A, len(sub))
+	for i, child := range sub {
+		folded[i] = Fold(child, f)
+	}
+
+	return f(term, folded)
+}
+
+// Find returns the first term in a tree that matches, searching parents before their children
+func Find(term Term, match func(term Term) bool) (Term, bool) {
+	var found Term
+
+	Walk(term, func(t Term) bool {
+		if found == nil && match(t) {
+			found = t
+		}
+		return found == nil
+	})
+
+	return found, found != nil
+}
+
+// Count returns the number of terms in a tree that match
+func Count(term Term, match func(term Term) bool) int {
+	n := 0
+
+	Walk(term, func(t Term) bool {
+		if match(t) {
+			n++
+		}
+		return true
+	})
+
+	return n
+}
@@ -14,6 +14,57 @@ Trig defines many trigonometric functions (all angles are radians):
   Cot => Cotangent of an angle
 */
 
+func init() {
+	register(TidSin, Registration{
+		Name:  "sin",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sin{X: args[0]}, nil
+		},
+		Type: Sin{},
+	})
+	register(TidCos, Registration{
+		Name:  "cos",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Cos{X: args[0]}, nil
+		},
+		Type: Cos{},
+	})
+	register(TidTan, Registration{
+		Name:  "tan",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Tan{X: args[0]}, nil
+		},
+		Type: Tan{},
+	})
+	register(TidSec, Registration{
+		Name:  "sec",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Sec{X: args[0]}, nil
+		},
+		Type: Sec{},
+	})
+	register(TidCsc, Registration{
+		Name:  "csc",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Csc{X: args[0]}, nil
+		},
+		Type: Csc{},
+	})
+	register(TidCot, Registration{
+		Name:  "cot",
+		Arity: 1,
+		New: func(_ float64, args []Term) (Term, error) {
+			return Cot{X: args[0]}, nil
+		},
+		Type: Cot{},
+	})
+}
+
 type Sin struct {
 	X Term
 }
@@ -36,9 +87,25 @@ func (e Sin) Dx() Term {
 }
 
 func (e Sin) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if !ok {
-		return Sin{e.X.T()}
+		// sin(asin(x)) = x wherever asin(x) is defined, which is only in [-1, 1]
+		if inv, ok := e.X.(Asin); ok {
+			return inv.X.T()
+		}
+
+		// sin(k pi) is exactly 0 for integer k, and 1 or -1 halfway between
+		if k, ok := piMultiple(e.X); ok {
+			if k == math.Trunc(k) {
+				return S(0)
+			} else if h := k - 0.5; h == math.Trunc(h) {
+				return S(1 - 2*mod(h, 2))
+			}
+		}
+
+		return Sin{e.X}
 	}
 
 	return S(math.Sin(val))
@@ -53,6 +120,15 @@ func (e Sin) Is() (bool, float64) {
 	return true, math.Sin(val)
 }
 
+func (e Sin) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Sin) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 type Cos struct {
 	X Term
 }
@@ -76,9 +152,25 @@ func (e Cos) Dx() Term {
 }
 
 func (e Cos) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if !ok {
-		return Cosh{e.X.T()}
+		// cos(acos(x)) = x wherever acos(x) is defined, which is only in [-1, 1]
+		if inv, ok := e.X.(Acos); ok {
+			return inv.X.T()
+		}
+
+		// cos(k pi) is exactly 1 or -1 for integer k, and 0 halfway between
+		if k, ok := piMultiple(e.X); ok {
+			if k == math.Trunc(k) {
+				return S(1 - 2*mod(k, 2))
+			} else if h := k - 0.5; h == math.Trunc(h) {
+				return S(0)
+			}
+		}
+
+		return Cos{e.X}
 	}
 
 	return S(math.Cos(val))
@@ -93,6 +185,15 @@ func (e Cos) Is() (bool, float64) {
 	return true, math.Cos(val)
 }
 
+func (e Cos) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Cos) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 type Tan struct {
 	X Term
 }
@@ -116,9 +217,20 @@ func (e Tan) Dx() Term {
 }
 
 func (e Tan) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if !ok {
-		return Tanh{e.X.T()}
+		// tan(atan(x)) = x for every x
+		if inv, ok := e.X.(Atan); ok {
+			return inv.X.T()
+		}
+
+		if k, ok := piMultiple(e.X); ok && k == math.Trunc(k) {
+			return S(0)
+		}
+
+		return Tan{e.X}
 	}
 
 	return S(math.Tan(val))
@@ -133,6 +245,15 @@ func (e Tan) Is() (bool, float64) {
 	return true, math.Tan(val)
 }
 
+func (e Tan) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Tan) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 type Sec struct {
 	X Term
 }
@@ -144,7 +265,7 @@ func (e Sec) Tokenise() Tokens {
 }
 
 func (e Sec) E(x float64) float64 {
-	return 1 / math.Cos(x)
+	return 1 / math.Cos(e.X.E(x))
 }
 
 func (e Sec) Dx() Term {
@@ -156,9 +277,16 @@ func (e Sec) Dx() Term {
 }
 
 func (e Sec) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if !ok {
-		return Sec{e.X.T()}
+		// sec(asec(x)) = x wherever asec(x) is defined, which is only outside (-1, 1)
+		if inv, ok := e.X.(Asec); ok {
+			return inv.X.T()
+		}
+
+		return Sec{e.X}
 	}
 
 	return S(1 / math.Cos(val))
@@ -173,6 +301,15 @@ func (e Sec) Is() (bool, float64) {
 	return true, 1 / math.Cos(val)
 }
 
+func (e Sec) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Sec) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 type Cot struct {
 	X Term
 }
@@ -197,9 +334,16 @@ func (e Cot) Dx() Term {
 }
 
 func (e Cot) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if !ok {
-		return Cot{e.X.T()}
+		// cot(acot(x)) = x for every x
+		if inv, ok := e.X.(Acot); ok {
+			return inv.X.T()
+		}
+
+		return Cot{e.X}
 	}
 
 	return S(1 / math.Tan(val))
@@ -214,6 +358,15 @@ func (e Cot) Is() (bool, float64) {
 	return true, 1 / math.Tan(val)
 }
 
+func (e Cot) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Cot) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
+
 type Csc struct {
 	X Term
 }
@@ -231,16 +384,23 @@ func (e Csc) E(x float64) float64 {
 func (e Csc) Dx() Term {
 	return Prod{
 		S(-1),
-		e.Dx(),
+		e.X.Dx(),
 		Csc{e.X},
-		Tan{e.X},
+		Cot{e.X},
 	}.T()
 }
 
 func (e Csc) T() Term {
+	e = simplified(e)
+
 	ok, val := e.X.Is()
 	if !ok {
-		return Csc{e.X.T()}
+		// csc(acsc(x)) = x wherever acsc(x) is defined, which is only outside (-1, 1)
+		if inv, ok := e.X.(Acsc); ok {
+			return inv.X.T()
+		}
+
+		return Csc{e.X}
 	}
 
 	return S(1 / math.Sin(val))
@@ -254,3 +414,12 @@ func (e Csc) Is() (bool, float64) {
 
 	return true, 1 / math.Sin(val)
 }
+
+func (e Csc) Children() []Term {
+	return []Term{e.X}
+}
+
+func (e Csc) WithChildren(children []Term) Term {
+	e.X = children[0]
+	return e
+}
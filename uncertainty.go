@@ -0,0 +1,92 @@
+package alg
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// Uncertain is a measured value, normally distributed with the given mean and standard deviation.
+type Uncertain struct {
+	Mean, Sigma float64
+}
+
+// Estimate is the mean and variance of a term evaluated on an uncertain value
+type Estimate struct {
+	Mean, Variance float64
+}
+
+// Sigma returns the standard deviation of the estimate
+func (e Estimate) Sigma() float64 {
+	return math.Sqrt(e.Variance)
+}
+
+// Terms only have the one variable, so every propagation here takes a single input.
+// Correlations between inputs can only be accounted for once terms have several variables.
+
+// PropagateLinear estimates the mean and variance of a term on an uncertain input, from its first order taylor expansion.
+// This is the usual f(x) ± |f'(x)| σ, which is exact for linear terms and good when σ is small compared to the curvature.
+func PropagateLinear(term Term, in Uncertain) (Estimate, error) {
+	d1 := term.Dx().E(in.Mean)
+
+	out := Estimate{
+		Mean:     term.E(in.Mean),
+		Variance: d1 * d1 * in.Sigma * in.Sigma,
+	}
+
+	return out, checkEstimate(out)
+}
+
+// PropagateQuadratic estimates the mean and variance of a term on an uncertain input, from its second order taylor expansion.
+// Unlike PropagateLinear, it accounts for the shift in the mean caused by curvature, as in E[x^2] = μ^2 + σ^2.
+func PropagateQuadratic(term Term, in Uncertain) (Estimate, error) {
+	c := NewDerivativeCache()
+	d1 := c.Dx(term)
+	d2 := c.Dx(d1)
+
+	v1, v2 := d1.E(in.Mean), d2.E(in.Mean)
+	s2 := in.Sigma * in.Sigma
+
+	// The normal distribution has E[(x-μ)^3] = 0 and E[(x-μ)^4] = 3σ^4
+	out := Estimate{
+		Mean:     term.E(in.Mean) + v2*s2/2,
+		Variance: v1*v1*s2 + v2*v2*s2*s2/2,
+	}
+
+	return out, checkEstimate(out)
+}
+
+// PropagateMonteCarlo estimates the mean and variance of a term on an uncertain input, by evaluating it on n samples drawn from rng.
+// It makes no assumptions about the term, but the error of the estimate only falls with the square root of n.
+func PropagateMonteCarlo(term Term, in Uncertain, n int, rng *rand.Rand) (Estimate, error) {
+	if n < 2 {
+		return Estimate{}, errors.New("need at least two samples")
+	}
+
+	// Welford's algorithm, which doesn't lose precision when the variance is small compared to the mean
+	var mean, m2 float64
+	for i := 0; i < n; i++ {
+		v := term.E(in.Mean + in.Sigma*rng.NormFloat64())
+		if !finite(v) {
+			return Estimate{}, errors.New("term is not finite on a sample")
+		}
+
+		delta := v - mean
+		mean += delta / float64(i+1)
+		m2 += delta * (v - mean)
+	}
+
+	return Estimate{
+		Mean:     mean,
+		Variance: m2 / float64(n-1),
+	}, nil
+}
+
+// checkEstimate returns an error if a taylor estimate is not finite, such as when the term is not differentiable at the mean
+func checkEstimate(e Estimate) error {
+	if !finite(e.Mean) || !finite(e.Variance) {
+		return errors.New("term is not differentiable at the mean")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package alg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DOT returns a graphviz graph of a term, for debugging terms too big to read as tokens.
+// Structurally equal subtrees are drawn once, as in NewDAG, and filled in if they are used more than once.
+func DOT(term Term) string {
+	d := NewDAG(term)
+
+	b := &strings.Builder{}
+	b.WriteString("digraph {\n\tordering=out;\n\tnode [shape=box];\n")
+
+	for i, n := range d.Nodes {
+		if n.Uses > 1 {
+			fmt.Fprintf(b, "\tn%d [label=%q, style=filled, fillcolor=lightgoldenrod];\n", i, fmt.Sprintf("%s\n%d uses", label(n.Term), n.Uses))
+		} else {
+			fmt.Fprintf(b, "\tn%d [label=%q];\n", i, label(n.Term))
+		}
+	}
+
+	for i, n := range d.Nodes {
+		for _, c := range n.Children {
+			fmt.Fprintf(b, "\tn%d -> n%d;\n", i, c)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// Tree returns an indented drawing of a term, with one term per line and its children below it
+func Tree(term Term) string {
+	b := &strings.Builder{}
+
+	var draw func(t Term, prefix, child string)
+	draw = func(t Term, prefix, child string) {
+		b.WriteString(prefix + label(t) + "\n")
+
+		sub := Children(t)
+		for i, c := range sub {
+			if i == len(sub)-1 {
+				draw(c, child+"└── ", child+"    ")
+			} else {
+				draw(c, child+"├── ", child+"│   ")
+			}
+		}
+	}
+
+	draw(term, "", "")
+
+	return b.String()
+}
+
+// label names a term by its type, followed by any fields that aren't terms, like the power of TP
+func label(term Term) string {
+	v := reflect.ValueOf(term)
+	name := v.Type().Name()
+
+	switch v.Kind() {
+	case reflect.Float64:
+		return fmt.Sprintf("%s %g", name, v.Float())
+	case reflect.Struct:
+		parts := []string{name}
+
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() || f.Type == reflect.TypeOf((*Term)(nil)).Elem() {
+				continue
+			}
+
+			parts = append(parts, fmt.Sprintf("%s=%v", f.Name, v.Field(i)))
+		}
+
+		return strings.Join(parts, " ")
+	}
+
+	return name
+}